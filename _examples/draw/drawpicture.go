@@ -33,7 +33,7 @@ type (
 	}
 )
 
-func createFramebuffer(file *os.File, dev *mode.Modeset) (framebuffer, error) {
+func createFramebuffer(file *drm.Device, dev *mode.Modeset) (framebuffer, error) {
 	fb, err := mode.CreateFB(file, dev.Width, dev.Height, 32)
 	if err != nil {
 		return framebuffer{}, fmt.Errorf("Failed to create framebuffer: %s", err.Error())
@@ -102,7 +102,7 @@ func draw(msets []msetData) {
 	time.Sleep(10 * time.Second)
 }
 
-func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.File) error {
+func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *drm.Device) error {
 	handle := mset.fb.handle
 	data := mset.fb.data
 	fb := mset.fb
@@ -123,7 +123,7 @@ func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.Fil
 	return modeset.SetCrtc(mset.mode, mset.savedCrtc)
 }
 
-func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *os.File) {
+func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *drm.Device) {
 	for _, mset := range msets {
 		destroyFramebuffer(modeset, mset, file)
 	}
@@ -34,7 +34,7 @@ type (
 	}
 )
 
-func createFramebuffer(file *os.File, dev *mode.Modeset) (framebuffer, error) {
+func createFramebuffer(file *drm.Device, dev *mode.Modeset) (framebuffer, error) {
 	fb, err := mode.CreateFB(file, dev.Width, dev.Height, 32)
 	if err != nil {
 		return framebuffer{}, fmt.Errorf("Failed to create framebuffer: %s", err.Error())
@@ -71,7 +71,7 @@ func createFramebuffer(file *os.File, dev *mode.Modeset) (framebuffer, error) {
 	return framebuf, nil
 }
 
-func draw(file *os.File, msets []msetData) {
+func draw(file *drm.Device, msets []msetData) {
 	var (
 		r, g, b       uint8
 		rUp, gUp, bUp = true, true, true
@@ -89,7 +89,7 @@ func draw(file *os.File, msets []msetData) {
 		b = nextColor(&bUp, b, 5)
 
 		for j := 0; j < len(msets); j++ {
-			mset := msets[j]
+			mset := &msets[j]
 			buf := &mset.fbs[mset.frontbuf^1]
 			for k := uint16(0); k < mset.mode.Height; k++ {
 				for s := uint16(0); s < mset.mode.Width; s++ {
@@ -99,16 +99,27 @@ func draw(file *os.File, msets []msetData) {
 				}
 			}
 
-			err := mode.SetCrtc(file, mset.mode.Crtc, buf.id, 0, 0, &mset.mode.Conn, 1, &mset.mode.Mode)
+			err := mode.PageFlip(file, mset.mode.Crtc, buf.id, mode.PageFlipEvent, uint64(j))
 			if err != nil {
 				log.Printf("[error] Cannot flip CRTC for connector %d: %s", mset.mode.Conn, err.Error())
 				return
 			}
-
-			mset.frontbuf ^= 1
 		}
 
-		time.Sleep(150 * time.Millisecond)
+		// wait for the flips, at the next vblank of each CRTC
+		for pending := len(msets); pending > 0; {
+			events, err := drm.ReadEvents(file)
+			if err != nil {
+				log.Printf("[error] Cannot read events: %s", err.Error())
+				return
+			}
+			for _, ev := range events {
+				if ev.Type == drm.EventFlipComplete {
+					msets[ev.UserData].frontbuf ^= 1
+					pending--
+				}
+			}
+		}
 	}
 }
 
@@ -128,7 +139,7 @@ func nextColor(up *bool, cur uint8, mod int) uint8 {
 	return next
 }
 
-func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.File) {
+func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *drm.Device) {
 	fbs := mset.fbs
 
 	for _, fb := range fbs {
@@ -160,7 +171,7 @@ func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.Fil
 	}
 }
 
-func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *os.File) {
+func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *drm.Device) {
 	for _, mset := range msets {
 		destroyFramebuffer(modeset, mset, file)
 	}
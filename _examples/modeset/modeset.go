@@ -33,7 +33,7 @@ type (
 	}
 )
 
-func createFramebuffer(file *os.File, dev *mode.Modeset) (framebuffer, error) {
+func createFramebuffer(file *drm.Device, dev *mode.Modeset) (framebuffer, error) {
 	fb, err := mode.CreateFB(file, dev.Width, dev.Height, 32)
 	if err != nil {
 		return framebuffer{}, fmt.Errorf("Failed to create framebuffer: %s", err.Error())
@@ -118,7 +118,7 @@ func nextColor(up *bool, cur uint8, mod int) uint8 {
 	return next
 }
 
-func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.File) error {
+func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *drm.Device) error {
 	handle := mset.fb.handle
 	data := mset.fb.data
 	fb := mset.fb
@@ -139,7 +139,7 @@ func destroyFramebuffer(modeset *mode.SimpleModeset, mset msetData, file *os.Fil
 	return modeset.SetCrtc(mset.mode, mset.savedCrtc)
 }
 
-func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *os.File) {
+func cleanup(modeset *mode.SimpleModeset, msets []msetData, file *drm.Device) {
 	for _, mset := range msets {
 		destroyFramebuffer(modeset, mset, file)
 	}
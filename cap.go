@@ -1,7 +1,10 @@
 package drm
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
 	"unsafe"
 
 	"github.com/NeowayLabs/drm/ioctl"
@@ -12,6 +15,26 @@ type (
 		id  uint64
 		val uint64
 	}
+
+	// Capabilities of a DRM driver, as returned by GetCapabilities.
+	// Capabilities unknown to the running kernel are left zero.
+	Capabilities struct {
+		DumbBuffer          bool
+		VBlankHighCRTC      bool
+		PreferredDepth      int
+		PreferShadow        bool
+		PrimeImport         bool
+		PrimeExport         bool
+		TimestampMonotonic  bool
+		AsyncPageFlip       bool
+		CursorSize          image.Point
+		AddFB2Modifiers     bool
+		PageFlipTarget      bool
+		CrtcInVBlankEvent   bool
+		SyncObj             bool
+		SyncObjTimeline     bool
+		AtomicAsyncPageFlip bool
+	}
 )
 
 const (
@@ -25,10 +48,31 @@ const (
 	CapCursorWidth
 	CapCursorHeight
 
-	CapAddFB2Modifiers = 0x10
+	CapAddFB2Modifiers     = 0x10
+	CapPageFlipTarget      = 0x11
+	CapCrtcInVBlankEvent   = 0x12
+	CapSyncObj             = 0x13
+	CapSyncObjTimeline     = 0x14
+	CapAtomicAsyncPageFlip = 0x15
+)
+
+// Bits of the CapPrime value.
+const (
+	PrimeCapImport uint64 = 1 << iota
+	PrimeCapExport
+)
+
+// Client capabilities, enabled with SetClientCap.
+const (
+	ClientCapStereo3D uint64 = iota + 1
+	ClientCapUniversalPlanes
+	ClientCapAtomic
+	ClientCapAspectRatio
+	ClientCapWritebackConnectors
+	ClientCapCursorPlaneHotspot
 )
 
-func HasDumbBuffer(file *os.File) bool {
+func HasDumbBuffer(file ioctl.File) bool {
 	cap, err := GetCap(file, CapDumbBuffer)
 	if err != nil {
 		return false
@@ -36,12 +80,107 @@ func HasDumbBuffer(file *os.File) bool {
 	return cap != 0
 }
 
-func GetCap(file *os.File, capid uint64) (uint64, error) {
+func GetCap(file ioctl.File, capid uint64) (uint64, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	cap := &capability{}
 	cap.id = capid
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLGetCap), uintptr(unsafe.Pointer(cap)))
+	err := ioctl.Call(file, uintptr(IOCTLGetCap), pins.Ptr(unsafe.Pointer(cap)))
 	if err != nil {
 		return 0, err
 	}
 	return cap.val, nil
 }
+
+// SetClientCap tells the driver the client supports the capability
+// capid. Enabling ClientCapAtomic also enables ClientCapUniversalPlanes
+// and ClientCapAspectRatio, and ClientCapWritebackConnectors and
+// ClientCapCursorPlaneHotspot can only be enabled after it.
+func SetClientCap(file ioctl.File, capid, val uint64) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	cap := &capability{
+		id:  capid,
+		val: val,
+	}
+	return ioctl.Call(file, uintptr(IOCTLSetClientCap),
+		pins.Ptr(unsafe.Pointer(cap)))
+}
+
+// GetCapabilities queries every capability of the driver.
+func GetCapabilities(file ioctl.File) (*Capabilities, error) {
+	return getCapabilities(func(capid uint64) (uint64, error) {
+		return GetCap(file, capid)
+	})
+}
+
+func getCapabilities(get func(capid uint64) (uint64, error)) (*Capabilities, error) {
+	var (
+		caps     Capabilities
+		firstErr error
+	)
+	val := func(capid uint64) uint64 {
+		v, err := get(capid)
+		// older kernels fail with EINVAL on the capabilities they
+		// don't know about
+		if err != nil && !errors.Is(err, ErrUnsupported) && firstErr == nil {
+			firstErr = err
+		}
+		return v
+	}
+	flag := func(capid uint64) bool { return val(capid) != 0 }
+
+	caps.DumbBuffer = flag(CapDumbBuffer)
+	caps.VBlankHighCRTC = flag(CapVBlankHighCRTC)
+	caps.PreferredDepth = int(val(CapDumbPreferredDepth))
+	caps.PreferShadow = flag(CapDumbPreferShadow)
+	prime := val(CapPrime)
+	caps.PrimeImport = prime&PrimeCapImport != 0
+	caps.PrimeExport = prime&PrimeCapExport != 0
+	caps.TimestampMonotonic = flag(CapTimestampMonotonic)
+	caps.AsyncPageFlip = flag(CapAsyncPageFlip)
+	caps.CursorSize = image.Pt(int(val(CapCursorWidth)),
+		int(val(CapCursorHeight)))
+	caps.AddFB2Modifiers = flag(CapAddFB2Modifiers)
+	caps.PageFlipTarget = flag(CapPageFlipTarget)
+	caps.CrtcInVBlankEvent = flag(CapCrtcInVBlankEvent)
+	caps.SyncObj = flag(CapSyncObj)
+	caps.SyncObjTimeline = flag(CapSyncObjTimeline)
+	caps.AtomicAsyncPageFlip = flag(CapAtomicAsyncPageFlip)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return &caps, nil
+}
+
+// String lists the capabilities, one per line.
+func (c *Capabilities) String() string {
+	var b strings.Builder
+	line := func(name string, val interface{}) {
+		if v, ok := val.(bool); ok {
+			val = "no"
+			if v {
+				val = "yes"
+			}
+		}
+		fmt.Fprintf(&b, "%-24s%v\n", name+":", val)
+	}
+	line("dumb buffer", c.DumbBuffer)
+	line("vblank high crtc", c.VBlankHighCRTC)
+	line("preferred depth", c.PreferredDepth)
+	line("prefer shadow", c.PreferShadow)
+	line("prime import", c.PrimeImport)
+	line("prime export", c.PrimeExport)
+	line("timestamp monotonic", c.TimestampMonotonic)
+	line("async page flip", c.AsyncPageFlip)
+	line("cursor size", fmt.Sprintf("%dx%d", c.CursorSize.X, c.CursorSize.Y))
+	line("addfb2 modifiers", c.AddFB2Modifiers)
+	line("page flip target", c.PageFlipTarget)
+	line("crtc in vblank event", c.CrtcInVBlankEvent)
+	line("syncobj", c.SyncObj)
+	line("syncobj timeline", c.SyncObjTimeline)
+	line("atomic async page flip", c.AtomicAsyncPageFlip)
+	return strings.TrimSuffix(b.String(), "\n")
+}
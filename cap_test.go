@@ -1,12 +1,17 @@
 package drm_test
 
 import (
+	"image"
+	"strings"
+	"syscall"
 	"testing"
 
 	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
 )
 
 func TestHasDumbBuffer(t *testing.T) {
+	needCard(t)
 	file, err := drm.OpenCard(0)
 	if err != nil {
 		t.Fatal(err)
@@ -24,6 +29,7 @@ func TestHasDumbBuffer(t *testing.T) {
 }
 
 func TestGetCap(t *testing.T) {
+	needCard(t)
 	file, err := drm.OpenCard(0)
 	if err != nil {
 		t.Fatal(err)
@@ -42,3 +48,76 @@ func TestGetCap(t *testing.T) {
 
 	}
 }
+
+func TestGetCapabilities(t *testing.T) {
+	card := drmtest.New()
+	card.Caps[drm.CapPrime] = drm.PrimeCapImport | drm.PrimeCapExport
+	card.Caps[drm.CapSyncObj] = 1
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	caps, err := drm.GetCapabilities(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := drm.Capabilities{
+		DumbBuffer:         true,
+		PreferredDepth:     24,
+		PreferShadow:       true,
+		PrimeImport:        true,
+		PrimeExport:        true,
+		TimestampMonotonic: true,
+		CursorSize:         image.Pt(64, 64),
+		SyncObj:            true,
+	}
+	if *caps != expected {
+		t.Errorf("Expected %+v but got %+v", expected, *caps)
+	}
+	str := caps.String()
+	for _, line := range []string{
+		"dumb buffer:            yes",
+		"preferred depth:        24",
+		"cursor size:            64x64",
+		"syncobj timeline:       no",
+	} {
+		if !strings.Contains(str, line) {
+			t.Errorf("Missing %q in:\n%s", line, str)
+		}
+	}
+
+	cached, err := dev.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cached != expected {
+		t.Errorf("Expected %+v but got %+v", expected, *cached)
+	}
+
+	card.Inject(drm.IOCTLGetCap, syscall.EBADF)
+	if _, err := drm.GetCapabilities(dev); err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestGetCapabilitiesCard(t *testing.T) {
+	needCard(t)
+	file, err := drm.OpenCard(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	caps, err := drm.GetCapabilities(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cardInfo.capabilities
+	if caps.DumbBuffer != (expected[drm.CapDumbBuffer] != 0) ||
+		uint64(caps.PreferredDepth) != expected[drm.CapDumbPreferredDepth] ||
+		uint64(caps.CursorSize.X) != expected[drm.CapCursorWidth] ||
+		uint64(caps.CursorSize.Y) != expected[drm.CapCursorHeight] {
+		t.Errorf("Unexpected capabilities:\n%s", caps)
+	}
+}
@@ -1,19 +1,47 @@
 package drm
 
-import (
-	"unsafe"
-
-	"github.com/NeowayLabs/drm/ioctl"
-)
+import "github.com/NeowayLabs/drm/ioctl"
 
 const IOCTLBase = 'd'
 
 var (
 	// DRM_IOWR(0x00, struct drm_version)
-	IOCTLVersion = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(version{})), IOCTLBase, 0)
+	IOCTLVersion = ioctl.Register("DRM_IOCTL_VERSION",
+		ioctl.IOWR(IOCTLBase, 0x00, version{}))
+
+	// DRM_IOW(0x0d, struct drm_set_client_cap)
+	IOCTLSetClientCap = ioctl.Register("DRM_IOCTL_SET_CLIENT_CAP",
+		ioctl.IOW(IOCTLBase, 0x0d, capability{}))
+
+	// DRM_IOR(0x02, struct drm_auth)
+	IOCTLGetMagic = ioctl.Register("DRM_IOCTL_GET_MAGIC",
+		ioctl.IOR(IOCTLBase, 0x02, auth{}))
 
 	// DRM_IOWR(0x0c, struct drm_get_cap)
-	IOCTLGetCap = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(capability{})), IOCTLBase, 0x0c)
+	IOCTLGetCap = ioctl.Register("DRM_IOCTL_GET_CAP",
+		ioctl.IOWR(IOCTLBase, 0x0c, capability{}))
+
+	// DRM_IOW(0x11, struct drm_auth)
+	IOCTLAuthMagic = ioctl.Register("DRM_IOCTL_AUTH_MAGIC",
+		ioctl.IOW(IOCTLBase, 0x11, auth{}))
+
+	// DRM_IO(0x1e)
+	IOCTLSetMaster = ioctl.Register("DRM_IOCTL_SET_MASTER",
+		ioctl.IO(IOCTLBase, 0x1e))
+
+	// DRM_IO(0x1f)
+	IOCTLDropMaster = ioctl.Register("DRM_IOCTL_DROP_MASTER",
+		ioctl.IO(IOCTLBase, 0x1f))
+
+	// DRM_IOWR(0x3a, union drm_wait_vblank)
+	IOCTLWaitVBlank = ioctl.Register("DRM_IOCTL_WAIT_VBLANK",
+		ioctl.IOWR(IOCTLBase, 0x3a, waitVBlank{}))
+
+	// DRM_IOWR(0x3b, struct drm_crtc_get_sequence)
+	IOCTLCrtcGetSequence = ioctl.Register("DRM_IOCTL_CRTC_GET_SEQUENCE",
+		ioctl.IOWR(IOCTLBase, 0x3b, crtcGetSequence{}))
+
+	// DRM_IOWR(0x3c, struct drm_crtc_queue_sequence)
+	IOCTLCrtcQueueSequence = ioctl.Register("DRM_IOCTL_CRTC_QUEUE_SEQUENCE",
+		ioctl.IOWR(IOCTLBase, 0x3c, crtcQueueSequence{}))
 )
@@ -0,0 +1,382 @@
+package drm
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"sync"
+
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/ioctl"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+type (
+	// Node is the type of a DRM device node
+	Node int
+
+	// Device is an open DRM device node. It caches the driver version
+	// and the capabilities already queried, remembers the client
+	// capabilities enabled, and releases every dumb buffer and
+	// framebuffer created through it when closed.
+	Device struct {
+		file    *os.File
+		fd      uintptr
+		node    Node
+		doer    ioctl.Doer
+		version Version
+
+		mu         sync.Mutex
+		caps       map[uint64]uint64
+		clientCaps map[uint64]uint64
+		dumbs      map[uint32]struct{} // dumb buffer handles
+		fbs        map[uint32]struct{} // framebuffer ids
+	}
+)
+
+const (
+	NodePrimary Node = iota // /dev/dri/cardN
+	NodeControl             // /dev/dri/controlDN
+	NodeRender              // /dev/dri/renderDN
+)
+
+func (n Node) String() string {
+	switch n {
+	case NodePrimary:
+		return "primary"
+	case NodeControl:
+		return "control"
+	case NodeRender:
+		return "render"
+	}
+	return "unknown"
+}
+
+// NewDevice makes a Device of an already open DRM node. Every request
+// on the device is issued through doer, or straight to the kernel if
+// doer is nil.
+func NewDevice(file *os.File, node Node, doer ioctl.Doer) (*Device, error) {
+	if doer == nil {
+		doer = ioctl.Kernel
+	}
+	fd, err := fileFd(file)
+	if err != nil {
+		return nil, err
+	}
+	dev := &Device{
+		file:       file,
+		fd:         fd,
+		node:       node,
+		doer:       doer,
+		caps:       make(map[uint64]uint64),
+		clientCaps: make(map[uint64]uint64),
+		dumbs:      make(map[uint32]struct{}),
+		fbs:        make(map[uint32]struct{}),
+	}
+	version, err := GetVersion(dev)
+	if err != nil {
+		return nil, err
+	}
+	dev.version = version
+	return dev, nil
+}
+
+// Fd returns the file descriptor of the device node. Unlike
+// os.File.Fd, it leaves the file in non-blocking mode, so the reads of
+// an EventLoop can still be interrupted.
+func (d *Device) Fd() uintptr { return d.fd }
+
+// fileFd returns the file descriptor of file without putting it in
+// blocking mode.
+func fileFd(file *os.File) (uintptr, error) {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd uintptr
+	err = conn.Control(func(f uintptr) { fd = f })
+	return fd, err
+}
+
+// Read reads the events of the device, see ReadEvents.
+func (d *Device) Read(p []byte) (int, error) { return d.file.Read(p) }
+
+// Doer returns the Doer issuing the requests on the device.
+func (d *Device) Doer() ioctl.Doer { return d.doer }
+
+// Node returns the type of the device node.
+func (d *Device) Node() Node { return d.node }
+
+// Version returns the driver version queried when the device was opened.
+func (d *Device) Version() Version { return d.version }
+
+// Cap returns the value of the capability capid. Values are cached
+// after the first successful query.
+func (d *Device) Cap(capid uint64) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if val, ok := d.caps[capid]; ok {
+		return val, nil
+	}
+	val, err := GetCap(d, capid)
+	if err != nil {
+		return 0, err
+	}
+	d.caps[capid] = val
+	return val, nil
+}
+
+// Capabilities returns every capability of the driver, sharing the
+// cache of Cap.
+func (d *Device) Capabilities() (*Capabilities, error) {
+	return getCapabilities(d.Cap)
+}
+
+func (d *Device) HasDumbBuffer() bool {
+	cap, err := d.Cap(CapDumbBuffer)
+	if err != nil {
+		return false
+	}
+	return cap != 0
+}
+
+// SetClientCap enables (or disables, if val is zero) the client
+// capability capid and remembers it, along with the capabilities the
+// kernel changes with it.
+func (d *Device) SetClientCap(capid, val uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := SetClientCap(d, capid, val); err != nil {
+		return err
+	}
+	d.clientCaps[capid] = val
+	if capid == ClientCapAtomic {
+		d.clientCaps[ClientCapUniversalPlanes] = val
+		d.clientCaps[ClientCapAspectRatio] = val
+	}
+	return nil
+}
+
+// ClientCap returns the value the client capability capid was set to,
+// or zero if it was never enabled. The methods depending on a client
+// capability check it, eg.: NewAtomicRequest.
+func (d *Device) ClientCap(capid uint64) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.clientCaps[capid]
+}
+
+func (d *Device) SetMaster() error { return SetMaster(d) }
+
+func (d *Device) DropMaster() error { return DropMaster(d) }
+
+func (d *Device) IsMaster() bool { return IsMaster(d) }
+
+func (d *Device) GetMagic() (Magic, error) { return GetMagic(d) }
+
+func (d *Device) AuthMagic(magic Magic) error { return AuthMagic(d, magic) }
+
+func (d *Device) GetResources() (*mode.Resources, error) {
+	return mode.GetResources(d)
+}
+
+func (d *Device) GetConnector(id uint32) (*mode.Connector, error) {
+	return mode.GetConnector(d, id)
+}
+
+func (d *Device) GetEncoder(id uint32) (*mode.Encoder, error) {
+	return mode.GetEncoder(d, id)
+}
+
+func (d *Device) GetCrtc(id uint32) (*mode.Crtc, error) {
+	return mode.GetCrtc(d, id)
+}
+
+func (d *Device) SetCrtc(crtcid, bufferid, x, y uint32, connectors *uint32, count int, info *mode.Info) error {
+	return mode.SetCrtc(d, crtcid, bufferid, x, y, connectors, count, info)
+}
+
+// GetPlaneResources lists the overlay planes, and the primary and
+// cursor planes too once ClientCapUniversalPlanes is enabled.
+func (d *Device) GetPlaneResources() (*mode.PlaneResources, error) {
+	return mode.GetPlaneResources(d)
+}
+
+func (d *Device) GetPlane(id uint32) (*mode.Plane, error) {
+	return mode.GetPlane(d, id)
+}
+
+func (d *Device) SetPlane(planeID, crtcID, fbID uint32, dst image.Rectangle, src mode.FixedRect) error {
+	return mode.SetPlane(d, planeID, crtcID, fbID, dst, src)
+}
+
+// GetPlaneType returns the type of the plane id. Without
+// ClientCapUniversalPlanes, only the overlay planes are listed, but
+// the type of a primary or cursor plane found otherwise (eg.: through
+// a CRTC) is still reported.
+func (d *Device) GetPlaneType(id uint32) (uint64, error) {
+	return mode.GetPlaneType(d, id)
+}
+
+func (d *Device) GetProperty(id uint32) (*mode.Property, error) {
+	return mode.GetProperty(d, id)
+}
+
+func (d *Device) GetObjectProperties(objID, objType uint32) (*mode.ObjectProperties, error) {
+	return mode.GetObjectProperties(d, objID, objType)
+}
+
+func (d *Device) SetObjectProperty(objID, objType, propID uint32, value uint64) error {
+	return mode.SetObjectProperty(d, objID, objType, propID, value)
+}
+
+func (d *Device) GetPropertySet(objID, objType uint32) (*mode.PropertySet, error) {
+	return mode.GetPropertySet(d, objID, objType)
+}
+
+func (d *Device) GetBlob(blobID uint32) ([]byte, error) {
+	return mode.GetBlob(d, blobID)
+}
+
+func (d *Device) CreateBlob(data []byte) (uint32, error) {
+	return mode.CreateBlob(d, data)
+}
+
+func (d *Device) DestroyBlob(blobID uint32) error {
+	return mode.DestroyBlob(d, blobID)
+}
+
+func (d *Device) CreateModeBlob(info *mode.Info) (uint32, error) {
+	return mode.CreateModeBlob(d, info)
+}
+
+// NewAtomicRequest returns an empty atomic request on the device,
+// failing with ErrNoClientCap unless ClientCapAtomic is enabled.
+func (d *Device) NewAtomicRequest() (*mode.AtomicRequest, error) {
+	if d.ClientCap(ClientCapAtomic) == 0 {
+		return nil, fmt.Errorf("%w: atomic requests need ClientCapAtomic",
+			ErrNoClientCap)
+	}
+	return mode.NewAtomicRequest(d), nil
+}
+
+// NewAtomicModeset enables the client capability ClientCapAtomic,
+// needed by the atomic modeset.
+func (d *Device) NewAtomicModeset() (*mode.AtomicModeset, error) {
+	if err := d.SetClientCap(ClientCapAtomic, 1); err != nil {
+		return nil, err
+	}
+	return mode.NewAtomicModeset(d)
+}
+
+func (d *Device) PageFlip(crtcID, fbID, flags uint32, userData uint64) error {
+	return mode.PageFlip(d, crtcID, fbID, flags, userData)
+}
+
+func (d *Device) PageFlipTarget(crtcID, fbID, flags, target uint32, userData uint64) error {
+	return mode.PageFlipTarget(d, crtcID, fbID, flags, target, userData)
+}
+
+func (d *Device) WaitVBlank(crtcIndex int, flags, sequence uint32, userData uint64) (VBlank, error) {
+	return vblankWait(d, d.Cap, crtcIndex, flags, sequence, userData)
+}
+
+func (d *Device) GetCrtcSequence(crtcID uint32) (CrtcSequence, error) {
+	return GetCrtcSequence(d, crtcID)
+}
+
+func (d *Device) QueueCrtcSequence(crtcID, flags uint32, sequence, userData uint64) (uint64, error) {
+	return QueueCrtcSequence(d, crtcID, flags, sequence, userData)
+}
+
+// ReadEvents reads the pending events of the device, see ReadEvents.
+func (d *Device) ReadEvents() ([]Event, error) { return ReadEvents(d) }
+
+// CreateFB creates a dumb buffer that is destroyed on Close unless
+// DestroyDumb is called before.
+func (d *Device) CreateFB(width, height uint16, bpp uint32) (*mode.FB, error) {
+	fb, err := mode.CreateFB(d, width, height, bpp)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.dumbs[fb.Handle] = struct{}{}
+	d.mu.Unlock()
+	return fb, nil
+}
+
+// AddFB adds a framebuffer that is removed on Close unless RmFB is
+// called before.
+func (d *Device) AddFB(width, height uint16, depth, bpp uint8, pitch, boHandle uint32) (uint32, error) {
+	id, err := mode.AddFB(d, width, height, depth, bpp, pitch, boHandle)
+	if err != nil {
+		return 0, err
+	}
+	d.mu.Lock()
+	d.fbs[id] = struct{}{}
+	d.mu.Unlock()
+	return id, nil
+}
+
+// AddFB2 adds a framebuffer that is removed on Close unless RmFB is
+// called before.
+func (d *Device) AddFB2(width, height uint16, format fourcc.Format, handles, pitches, offsets [4]uint32, modifiers [4]fourcc.Modifier, flags uint32) (uint32, error) {
+	id, err := mode.AddFB2(d, width, height, format, handles, pitches,
+		offsets, modifiers, flags)
+	if err != nil {
+		return 0, err
+	}
+	d.mu.Lock()
+	d.fbs[id] = struct{}{}
+	d.mu.Unlock()
+	return id, nil
+}
+
+func (d *Device) RmFB(bufferid uint32) error {
+	d.mu.Lock()
+	delete(d.fbs, bufferid)
+	d.mu.Unlock()
+	return mode.RmFB(d, bufferid)
+}
+
+func (d *Device) MapDumb(boHandle uint32) (uint64, error) {
+	return mode.MapDumb(d, boHandle)
+}
+
+func (d *Device) DestroyDumb(handle uint32) error {
+	d.mu.Lock()
+	delete(d.dumbs, handle)
+	d.mu.Unlock()
+	return mode.DestroyDumb(d, handle)
+}
+
+func (d *Device) NewSimpleModeset() (*mode.SimpleModeset, error) {
+	return mode.NewSimpleModeset(d)
+}
+
+// Close removes the framebuffers and destroys the dumb buffers created
+// through the device, then closes the device node. The first error
+// found is returned.
+func (d *Device) Close() error {
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	d.mu.Lock()
+	fbs, dumbs := d.fbs, d.dumbs
+	d.fbs = make(map[uint32]struct{})
+	d.dumbs = make(map[uint32]struct{})
+	d.mu.Unlock()
+
+	for id := range fbs {
+		keep(mode.RmFB(d, id))
+	}
+	for handle := range dumbs {
+		keep(mode.DestroyDumb(d, handle))
+	}
+	keep(d.file.Close())
+	return firstErr
+}
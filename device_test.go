@@ -0,0 +1,154 @@
+package drm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+)
+
+func TestFakeDevice(t *testing.T) {
+	card := drmtest.New()
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	if v := dev.Version(); v.Name != "drmtest" || v.Desc != "fake drm card" {
+		t.Errorf("Unexpected version: %+v", v)
+	}
+	if dev.Node() != drm.NodePrimary {
+		t.Errorf("Unexpected node %s", dev.Node())
+	}
+	if !dev.HasDumbBuffer() {
+		t.Errorf("Fake card should support dumb buffers")
+	}
+
+	depth, err := dev.Cap(drm.CapDumbPreferredDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.Caps[drm.CapDumbPreferredDepth] = 16
+	if cached, _ := dev.Cap(drm.CapDumbPreferredDepth); cached != depth {
+		t.Errorf("Capability not cached: %d != %d", cached, depth)
+	}
+	if _, err := dev.Cap(drm.CapAsyncPageFlip); err == nil {
+		t.Errorf("Expected error for unknown capability")
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	// struct drm_version is 36 bytes on 32 bits systems, 64 on 64 bits
+	expected := uint32(0xc0406400)
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		expected = 0xc0246400
+	}
+	if drm.IOCTLVersion != expected {
+		t.Errorf("Expected DRM_IOCTL_VERSION %#x but got %#x",
+			expected, drm.IOCTLVersion)
+	}
+
+	card := drmtest.New()
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	card.Version.Date = "20240101"
+	v, err := drm.GetVersion(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "drmtest" || v.Date != "20240101" || v.Desc != "fake drm card" {
+		t.Errorf("Unexpected version: %+v", v)
+	}
+	card.Version.Desc = strings.Repeat("x", 1<<20)
+	if _, err := drm.GetVersion(dev); err == nil {
+		t.Errorf("Expected error for a too long description")
+	}
+}
+
+func TestDeviceCloseReleasesBuffers(t *testing.T) {
+	card := drmtest.New()
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		fb, err := dev.CreateFB(640, 480, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dev.AddFB(640, 480, 24, 32, fb.Pitch, fb.Handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fb, err := dev.CreateFB(640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.DestroyDumb(fb.Handle); err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Framebuffers) != 2 || len(card.DumbBuffers) != 2 {
+		t.Fatalf("Unexpected buffers: %v %v", card.Framebuffers,
+			card.DumbBuffers)
+	}
+
+	if err := dev.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Framebuffers) != 0 || len(card.DumbBuffers) != 0 {
+		t.Errorf("Buffers left behind: %v %v", card.Framebuffers,
+			card.DumbBuffers)
+	}
+}
+
+func TestSetClientCap(t *testing.T) {
+	card := drmtest.New()
+	delete(card.ClientCaps, drm.ClientCapStereo3D)
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	err = dev.SetClientCap(drm.ClientCapWritebackConnectors, 1)
+	if !errors.Is(err, drm.ErrUnsupported) {
+		t.Errorf("Expected %v before atomic but got %v", drm.ErrUnsupported, err)
+	}
+	if err := dev.SetClientCap(drm.ClientCapStereo3D, 1); !errors.Is(err, drm.ErrUnsupported) {
+		t.Errorf("Expected %v but got %v", drm.ErrUnsupported, err)
+	}
+
+	if _, err := dev.NewAtomicRequest(); !errors.Is(err, drm.ErrNoClientCap) {
+		t.Errorf("Expected %v before atomic but got %v", drm.ErrNoClientCap, err)
+	}
+	if err := dev.SetClientCap(drm.ClientCapAtomic, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.NewAtomicRequest(); err != nil {
+		t.Errorf("Unexpected error after enabling atomic: %v", err)
+	}
+	if err := dev.SetClientCap(drm.ClientCapWritebackConnectors, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, capid := range []uint64{
+		drm.ClientCapAtomic,
+		drm.ClientCapUniversalPlanes,
+		drm.ClientCapAspectRatio,
+		drm.ClientCapWritebackConnectors,
+	} {
+		if dev.ClientCap(capid) != 1 || card.ClientCap(dev.Fd(), capid) != 1 {
+			t.Errorf("Client cap %d not enabled", capid)
+		}
+	}
+	if dev.ClientCap(drm.ClientCapStereo3D) != 0 {
+		t.Errorf("Failed client cap remembered")
+	}
+}
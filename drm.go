@@ -13,15 +13,17 @@ import (
 )
 
 type (
+	// struct drm_version: the lengths are size_t, as wide as the
+	// pointers
 	version struct {
 		Major   int32
 		Minor   int32
 		Patch   int32
-		namelen int64
+		namelen uint
 		name    uintptr
-		datelen int64
+		datelen uint
 		date    uintptr
-		desclen int64
+		desclen uint
 		desc    uintptr
 	}
 
@@ -38,71 +40,94 @@ type (
 
 const (
 	driPath = "/dev/dri"
+
+	// maxVersionLen bounds the strings of GetVersion, the driver
+	// name, date and description are a few bytes long.
+	maxVersionLen = 1 << 16
 )
 
 func Available() (Version, error) {
-	f, err := OpenCard(0)
+	dev, err := OpenCard(0)
 	if err != nil {
 		// handle backward linux compat?
 		// check /proc/dri/0 ?
 		return Version{}, err
 	}
-	defer f.Close()
-	return GetVersion(f)
+	defer dev.Close()
+	return dev.Version(), nil
 }
 
-func OpenCard(n int) (*os.File, error) {
-	return open(fmt.Sprintf("%s/card%d", driPath, n))
+func OpenCard(n int) (*Device, error) {
+	return open(fmt.Sprintf("%s/card%d", driPath, n), NodePrimary)
 }
 
-func OpenControlDev(n int) (*os.File, error) {
-	return open(fmt.Sprintf("%s/controlD%d", driPath, n))
+func OpenControlDev(n int) (*Device, error) {
+	return open(fmt.Sprintf("%s/controlD%d", driPath, n), NodeControl)
 }
 
-func OpenRenderDev(n int) (*os.File, error) {
-	return open(fmt.Sprintf("%s/renderD%d", driPath, n))
+func OpenRenderDev(n int) (*Device, error) {
+	return open(fmt.Sprintf("%s/renderD%d", driPath, n), NodeRender)
 }
 
-func open(path string) (*os.File, error) {
-	return os.OpenFile(path, os.O_RDWR, 0)
+func open(path string, node Node) (*Device, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	dev, err := NewDevice(file, node, nil)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return dev, nil
 }
 
-func GetVersion(file *os.File) (Version, error) {
+func GetVersion(file ioctl.File) (Version, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	var (
 		name, date, desc []byte
 	)
 
 	version := &version{}
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLVersion),
-		uintptr(unsafe.Pointer(version)))
+	err := ioctl.Call(file, uintptr(IOCTLVersion),
+		pins.Ptr(unsafe.Pointer(version)))
 	if err != nil {
 		return Version{}, err
 	}
 
+	if version.namelen > maxVersionLen || version.datelen > maxVersionLen ||
+		version.desclen > maxVersionLen {
+		return Version{}, fmt.Errorf("drm: version strings of %d, %d and %d bytes",
+			version.namelen, version.datelen, version.desclen)
+	}
+
 	if version.namelen > 0 {
 		name = make([]byte, version.namelen+1)
-		version.name = uintptr(unsafe.Pointer(&name[0]))
+		version.name = pins.Ptr(unsafe.Pointer(&name[0]))
 	}
 
 	if version.datelen > 0 {
 		date = make([]byte, version.datelen+1)
-		version.date = uintptr(unsafe.Pointer(&date[0]))
+		version.date = pins.Ptr(unsafe.Pointer(&date[0]))
 	}
 	if version.desclen > 0 {
 		desc = make([]byte, version.desclen+1)
-		version.desc = uintptr(unsafe.Pointer(&desc[0]))
+		version.desc = pins.Ptr(unsafe.Pointer(&desc[0]))
 	}
 
-	err = ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLVersion),
-		uintptr(unsafe.Pointer(version)))
+	err = ioctl.Call(file, uintptr(IOCTLVersion),
+		pins.Ptr(unsafe.Pointer(version)))
 	if err != nil {
 		return Version{}, err
 	}
 
-	// remove C null byte at end
-	name = name[:version.namelen]
-	date = date[:version.datelen]
-	desc = desc[:version.desclen]
+	// remove C null byte at end, the strings are truncated if they
+	// grew between the calls
+	name = name[:min(version.namelen, uint(len(name)))]
+	date = date[:min(version.datelen, uint(len(date)))]
+	desc = desc[:min(version.desclen, uint(len(desc)))]
 
 	nozero := func(r rune) bool {
 		if r == 0 {
@@ -146,15 +171,12 @@ func ListDevices() []Version {
 			continue
 		}
 
-		devfile, err := OpenCard(id)
-		if err != nil {
-			continue
-		}
-		dev, err := GetVersion(devfile)
+		dev, err := OpenCard(id)
 		if err != nil {
 			continue
 		}
-		devices = append(devices, dev)
+		devices = append(devices, dev.Version())
+		dev.Close()
 	}
 
 	return devices
@@ -8,6 +8,7 @@ import (
 )
 
 func TestDRIOpen(t *testing.T) {
+	needCard(t)
 	file, err := drm.OpenCard(0)
 	if err != nil {
 		t.Fatal(err)
@@ -16,6 +17,7 @@ func TestDRIOpen(t *testing.T) {
 }
 
 func TestAvailableCard(t *testing.T) {
+	needCard(t)
 	v, err := drm.Available()
 	if err != nil {
 		t.Fatal(err)
@@ -35,6 +37,7 @@ func TestAvailableCard(t *testing.T) {
 }
 
 func TestModeRes(t *testing.T) {
+	needCard(t)
 	file, err := drm.OpenCard(0)
 	if err != nil {
 		t.Fatal(err)
@@ -55,3 +58,28 @@ func TestModeRes(t *testing.T) {
 	t.Logf("Connector ids: %v", mres.Connectors)
 	t.Logf("Encoder ids: %v", mres.Encoders)
 }
+
+func TestDevice(t *testing.T) {
+	needCard(t)
+	dev, err := drm.OpenCard(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if dev.Node() != drm.NodePrimary {
+		t.Errorf("Expected %s node but got %s", drm.NodePrimary, dev.Node())
+	}
+	if v := dev.Version(); v.Name != card.Name {
+		t.Errorf("Expected driver %s but got %s", card.Name, v.Name)
+	}
+	for i := 0; i < 2; i++ {
+		ccap, err := dev.Cap(drm.CapDumbBuffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ccap != cardInfo.capabilities[drm.CapDumbBuffer] {
+			t.Errorf("Capability differs: %d != %d", ccap,
+				cardInfo.capabilities[drm.CapDumbBuffer])
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package drmtest
+
+import (
+	"image"
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+type (
+	crtcState struct {
+		active     bool
+		modeBlob   uint32
+		mode       *mode.Info
+		connectors []uint32
+	}
+
+	planeState struct {
+		fb, crtc uint32
+		src      mode.FixedRect
+		dst      image.Rectangle
+	}
+
+	// atomicState is the mode-setting state an atomic commit changes.
+	atomicState struct {
+		crtcs  map[uint32]*crtcState
+		planes map[uint32]*planeState
+	}
+)
+
+const atomicFlags = mode.PageFlipEvent | mode.PageFlipAsync |
+	mode.AtomicTestOnly | mode.AtomicNonblock | mode.AtomicAllowModeset
+
+func (c *Card) currentState() *atomicState {
+	state := &atomicState{
+		crtcs:  make(map[uint32]*crtcState),
+		planes: make(map[uint32]*planeState),
+	}
+	for _, crtc := range c.Crtcs {
+		state.crtcs[crtc.ID] = &crtcState{
+			active:     crtc.Active,
+			modeBlob:   crtc.ModeBlob,
+			mode:       crtc.Mode,
+			connectors: append([]uint32(nil), crtc.Connectors...),
+		}
+	}
+	for _, plane := range c.Planes {
+		state.planes[plane.ID] = &planeState{
+			fb:   plane.FbID,
+			crtc: plane.CrtcID,
+			src:  plane.Src,
+			dst:  plane.Dst,
+		}
+	}
+	return state
+}
+
+func (c *Card) atomic(fd uintptr, req *sysAtomic) error {
+	if req.flags&^atomicFlags != 0 || req.reserved != 0 ||
+		req.flags&(mode.AtomicTestOnly|mode.PageFlipEvent) ==
+			mode.AtomicTestOnly|mode.PageFlipEvent {
+		return syscall.EINVAL
+	}
+	if c.clientCaps[fd][drm.ClientCapAtomic] == 0 {
+		return syscall.EINVAL
+	}
+
+	var (
+		objs, countProps, props []uint32
+		values                  []uint64
+	)
+	if req.countObjs > 0 {
+		objs = unsafe.Slice((*uint32)(userPtr(req.objsPtr)), req.countObjs)
+		countProps = unsafe.Slice((*uint32)(userPtr(req.countPropsPtr)), req.countObjs)
+	}
+	var total uint32
+	for _, n := range countProps {
+		total += n
+	}
+	if total > 0 {
+		props = unsafe.Slice((*uint32)(userPtr(req.propsPtr)), total)
+		values = unsafe.Slice((*uint64)(userPtr(req.propValuesPtr)), total)
+	}
+
+	state := c.currentState()
+	for i, id := range objs {
+		objProps, _, ok := c.objectProps(id, mode.ObjectAny)
+		if !ok {
+			return syscall.ENOENT
+		}
+		for j := uint32(0); j < countProps[i]; j++ {
+			propID, value := props[0], values[0]
+			props, values = props[1:], values[1:]
+
+			prop := c.propertyByID(propID)
+			if prop == nil || !contains(objProps, propID) {
+				return syscall.ENOENT
+			}
+			if !c.validValue(prop, value) {
+				return syscall.EINVAL
+			}
+			if err := c.setAtomicProp(state, id, prop, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.checkState(state, req.flags); err != nil {
+		return err
+	}
+	if req.flags&mode.AtomicTestOnly != 0 {
+		return nil
+	}
+	crtcs, err := c.atomicFlips(state, objs)
+	if err != nil {
+		return err
+	}
+	c.applyState(state)
+	if req.flags&mode.PageFlipEvent != 0 {
+		for _, id := range crtcs {
+			crtc := c.crtc(id)
+			c.flips[id] = &flip{
+				fd:       fd,
+				event:    true,
+				userData: req.userData,
+				target:   crtc.Sequence + 1,
+			}
+		}
+	}
+	return nil
+}
+
+func contains[T comparable](ids []T, id T) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// setAtomicProp changes state as setting the property prop of the
+// object id to value does.
+func (c *Card) setAtomicProp(state *atomicState, id uint32, prop *Property, value uint64) error {
+	if crtc, ok := state.crtcs[id]; ok {
+		switch prop.Name {
+		case "ACTIVE":
+			crtc.active = value != 0
+		case "MODE_ID":
+			if value == 0 {
+				crtc.modeBlob, crtc.mode = 0, nil
+				return nil
+			}
+			blob, ok := c.Blobs[uint32(value)]
+			if !ok {
+				return syscall.EINVAL
+			}
+			info, err := mode.InfoFromBytes(blob.Data)
+			if err != nil {
+				return syscall.EINVAL
+			}
+			crtc.modeBlob, crtc.mode = blob.ID, info
+		}
+		return nil
+	}
+	if conn := c.connector(id); conn != nil {
+		if prop.Name != "CRTC_ID" {
+			return nil
+		}
+		for _, crtc := range state.crtcs {
+			crtc.connectors = without(crtc.connectors, []uint32{id})
+		}
+		if value == 0 {
+			return nil
+		}
+		crtc, ok := state.crtcs[uint32(value)]
+		if !ok {
+			return syscall.ENOENT
+		}
+		crtc.connectors = append(crtc.connectors, id)
+		return nil
+	}
+	plane, ok := state.planes[id]
+	if !ok {
+		return nil
+	}
+	switch prop.Name {
+	case "FB_ID":
+		if _, ok := c.Framebuffers[uint32(value)]; value != 0 && !ok {
+			return syscall.ENOENT
+		}
+		plane.fb = uint32(value)
+	case "CRTC_ID":
+		if _, ok := state.crtcs[uint32(value)]; value != 0 && !ok {
+			return syscall.ENOENT
+		}
+		plane.crtc = uint32(value)
+	case "SRC_X":
+		plane.src.X = mode.Fixed16(value)
+	case "SRC_Y":
+		plane.src.Y = mode.Fixed16(value)
+	case "SRC_W":
+		plane.src.W = mode.Fixed16(value)
+	case "SRC_H":
+		plane.src.H = mode.Fixed16(value)
+	case "CRTC_X":
+		plane.dst = image.Rect(int(int64(value)), plane.dst.Min.Y,
+			int(int64(value))+plane.dst.Dx(), plane.dst.Max.Y)
+	case "CRTC_Y":
+		plane.dst = image.Rect(plane.dst.Min.X, int(int64(value)),
+			plane.dst.Max.X, int(int64(value))+plane.dst.Dy())
+	case "CRTC_W":
+		plane.dst.Max.X = plane.dst.Min.X + int(value)
+	case "CRTC_H":
+		plane.dst.Max.Y = plane.dst.Min.Y + int(value)
+	}
+	return nil
+}
+
+// checkState validates the new state like the atomic check of the
+// drivers does, roughly.
+func (c *Card) checkState(state *atomicState, flags uint32) error {
+	modeset := false
+	for _, crtc := range c.Crtcs {
+		next := state.crtcs[crtc.ID]
+		if next.active != crtc.Active || next.modeBlob != crtc.ModeBlob ||
+			!sameIDs(next.connectors, crtc.Connectors) {
+			modeset = true
+		}
+		if next.active && next.mode == nil {
+			return syscall.EINVAL
+		}
+		if (next.mode != nil) != (len(next.connectors) > 0) {
+			return syscall.EINVAL
+		}
+		index := uint32(1) << uint(c.crtcIndex(crtc.ID))
+		for _, id := range next.connectors {
+			if !c.canDrive(c.connector(id), index) {
+				return syscall.EINVAL
+			}
+		}
+	}
+	if modeset && flags&mode.AtomicAllowModeset == 0 {
+		return syscall.EINVAL
+	}
+
+	for _, plane := range c.Planes {
+		next := state.planes[plane.ID]
+		if (next.fb == 0) != (next.crtc == 0) {
+			return syscall.EINVAL
+		}
+		if next.fb == 0 {
+			continue
+		}
+		crtc := state.crtcs[next.crtc]
+		index := c.crtcIndex(next.crtc)
+		if !crtc.active || plane.PossibleCrtcs&(1<<uint(index)) == 0 {
+			return syscall.EINVAL
+		}
+		fb := c.Framebuffers[next.fb]
+		if !contains(plane.Formats, fb.Format) {
+			return syscall.EINVAL
+		}
+		width, height := uint64(fb.Width)<<16, uint64(fb.Height)<<16
+		if uint64(next.src.W) > width ||
+			uint64(next.src.X) > width-uint64(next.src.W) ||
+			uint64(next.src.H) > height ||
+			uint64(next.src.Y) > height-uint64(next.src.H) {
+			return syscall.ENOSPC
+		}
+	}
+	return nil
+}
+
+// canDrive tells if one of the encoders of conn can drive the CRTCs in
+// the bitmask crtcs.
+func (c *Card) canDrive(conn *Connector, crtcs uint32) bool {
+	for _, id := range conn.Encoders {
+		if encoder := c.encoder(id); encoder != nil &&
+			encoder.PossibleCrtcs&crtcs != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIDs(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		if !contains(b, id) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Card) applyState(state *atomicState) {
+	for _, crtc := range c.Crtcs {
+		next := state.crtcs[crtc.ID]
+		crtc.Active = next.active
+		crtc.ModeBlob = next.modeBlob
+		crtc.Mode = next.mode
+		crtc.Connectors = next.connectors
+		crtc.FbID = 0
+		if primary, ok := state.planes[crtc.Primary]; ok &&
+			primary.crtc == crtc.ID {
+			crtc.FbID = primary.fb
+			crtc.X, crtc.Y = uint32(primary.src.X.Int()),
+				uint32(primary.src.Y.Int())
+		}
+		for _, id := range crtc.Connectors {
+			conn := c.connector(id)
+			index := uint32(1) << uint(c.crtcIndex(crtc.ID))
+			for _, encoderID := range conn.Encoders {
+				encoder := c.encoder(encoderID)
+				if encoder != nil && encoder.PossibleCrtcs&index != 0 {
+					conn.EncoderID = encoder.ID
+					encoder.CrtcID = crtc.ID
+					break
+				}
+			}
+		}
+	}
+	for _, plane := range c.Planes {
+		next := state.planes[plane.ID]
+		plane.FbID = next.fb
+		plane.CrtcID = next.crtc
+		plane.Src = next.src
+		plane.Dst = next.dst
+	}
+	c.releaseBlobs()
+}
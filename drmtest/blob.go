@@ -0,0 +1,104 @@
+package drmtest
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// Blob is a property blob.
+type Blob struct {
+	ID   uint32
+	Data []byte
+
+	owner    uintptr // fd of the client that created it
+	hasOwner bool    // false for the blobs created by the driver
+
+	// the blob is freed once no CRTC uses it: the mode blobs created
+	// by the driver, and the destroyed blobs still in use
+	transient bool
+}
+
+// AddBlob adds a blob created by the driver (eg.: an EDID) holding a
+// copy of data.
+func (c *Card) AddBlob(data []byte) *Blob {
+	c.Lock()
+	defer c.Unlock()
+	blob := &Blob{
+		ID:   c.newID(),
+		Data: append([]byte(nil), data...),
+	}
+	c.Blobs[blob.ID] = blob
+	return blob
+}
+
+func (c *Card) getBlob(req *sysGetBlob) error {
+	blob, ok := c.Blobs[req.id]
+	if !ok {
+		return syscall.ENOENT
+	}
+	// like the kernel, the data is copied only if the sizes match
+	n := uint32(len(blob.Data))
+	if req.length == n && n > 0 {
+		copy(unsafe.Slice((*byte)(userPtr(req.data)), n), blob.Data)
+	}
+	req.length = n
+	return nil
+}
+
+func (c *Card) createBlob(fd uintptr, req *sysCreateBlob) error {
+	if req.length == 0 {
+		return syscall.EINVAL
+	}
+	blob := &Blob{
+		ID: c.newID(),
+		Data: append([]byte(nil),
+			unsafe.Slice((*byte)(userPtr(req.data)), req.length)...),
+		owner:    fd,
+		hasOwner: true,
+	}
+	c.Blobs[blob.ID] = blob
+	req.id = blob.ID
+	return nil
+}
+
+func (c *Card) destroyBlob(fd uintptr, id *uint32) error {
+	blob, ok := c.Blobs[*id]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if !blob.hasOwner || blob.owner != fd {
+		return syscall.EPERM
+	}
+	// like the kernel, the CRTCs using the blob keep it alive
+	blob.hasOwner = false
+	blob.transient = true
+	c.releaseBlobs()
+	return nil
+}
+
+// modeBlob returns a blob created by the driver holding info, freed
+// once no CRTC uses it.
+func (c *Card) modeBlob(info *mode.Info) uint32 {
+	blob := &Blob{
+		ID:        c.newID(),
+		Data:      info.Bytes(),
+		transient: true,
+	}
+	c.Blobs[blob.ID] = blob
+	return blob.ID
+}
+
+// releaseBlobs frees the transient blobs no CRTC uses anymore.
+func (c *Card) releaseBlobs() {
+	used := make(map[uint32]bool)
+	for _, crtc := range c.Crtcs {
+		used[crtc.ModeBlob] = true
+	}
+	for id, blob := range c.Blobs {
+		if blob.transient && !used[id] {
+			delete(c.Blobs, id)
+		}
+	}
+}
@@ -0,0 +1,945 @@
+// Package drmtest provides an in-memory fake DRM card to test code
+// using the drm and mode packages on machines without a GPU.
+//
+// The fake implements ioctl.Doer: it decodes the requests issued by
+// the library with the same memory layout the kernel uses and keeps
+// the state of the mode-setting objects in Go values.
+package drmtest
+
+import (
+	"image"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+type (
+	Connector struct {
+		ID, EncoderID uint32
+		Type, TypeID  uint32
+		Connection    uint32
+		Width, Height uint32 // in millimeters
+		Subpixel      uint32
+
+		Modes      []mode.Info
+		Encoders   []uint32
+		Props      []uint32
+		PropValues []uint64
+	}
+
+	Encoder struct {
+		ID, Type       uint32
+		CrtcID         uint32
+		PossibleCrtcs  uint32
+		PossibleClones uint32
+	}
+
+	Crtc struct {
+		ID         uint32
+		FbID       uint32
+		X, Y       uint32
+		Mode       *mode.Info // nil if no mode is set
+		GammaSize  uint32
+		Connectors []uint32
+
+		Active   bool
+		ModeBlob uint32 // blob holding Mode
+		Primary  uint32 // primary plane
+
+		// Sequence counts the vblanks, see Card.VBlank, and VBlankTime
+		// is the time of the last one since the card was created, in
+		// microseconds like the events.
+		Sequence   uint64
+		VBlankTime time.Duration
+
+		Props      []uint32
+		PropValues []uint64
+	}
+
+	Framebuffer struct {
+		ID            uint32
+		Width, Height uint32
+		Pitch         uint32
+		BPP, Depth    uint32
+		Format        fourcc.Format
+		Handle        uint32
+
+		// planes of the framebuffers added with AddFB2, the first one
+		// is also in Pitch and Handle
+		Flags    uint32
+		Handles  [4]uint32
+		Pitches  [4]uint32
+		Offsets  [4]uint32
+		Modifier fourcc.Modifier // with the mode.FBModifiers flag
+	}
+
+	DumbBuffer struct {
+		Handle        uint32
+		Width, Height uint32
+		BPP           uint32
+		Pitch         uint32
+		Size          uint64
+	}
+
+	// Card is a fake DRM card. Its exported fields can be changed to
+	// set up the scenario under test, but only while no request is in
+	// flight (use Lock/Unlock from other goroutines).
+	Card struct {
+		sync.Mutex
+
+		Version drm.Version
+		Caps    map[uint64]uint64
+
+		// ClientCaps are the client capabilities the driver supports.
+		ClientCaps map[uint64]bool
+
+		Crtcs      []*Crtc
+		Encoders   []*Encoder
+		Connectors []*Connector
+		Planes     []*Plane
+		Properties []*Property
+
+		Framebuffers map[uint32]*Framebuffer
+		DumbBuffers  map[uint32]*DumbBuffer
+		Blobs        map[uint32]*Blob
+
+		// BeforeRequest, if set, is called before the card handles
+		// each request, without holding the lock. Tests use it to
+		// change the card in-between the requests of a call (eg.: to
+		// hotplug a monitor).
+		BeforeRequest func(code uint32)
+
+		// CollectGarbage makes the card run the garbage collector
+		// before handling each request, so buffers the library does
+		// not keep alive until the request returns get collected.
+		// Run with GODEBUG=clobberfree=1 to have them overwritten.
+		CollectGarbage bool
+
+		nextID     uint32
+		nextHandle uint32
+		pipes      map[uintptr]*os.File // event pipe of each fd
+		flips      map[uint32]*flip     // pending, by CRTC id
+		waits      []*vblankWait        // events queued for a vblank
+		vblank     *sync.Cond           // signaled on each VBlank
+		created    time.Time
+		injected   map[uint32][]syscall.Errno
+
+		master    uintptr // fd of the DRM master
+		hasMaster bool
+
+		magics        map[uint32]uintptr // fd of each magic
+		nextMagic     uint32
+		authenticated map[uintptr]bool
+
+		clientCaps map[uintptr]map[uint64]uint64 // enabled, by fd
+	}
+)
+
+// New returns a card without any mode-setting object.
+func New() *Card {
+	c := &Card{
+		Version: drm.Version{
+			Major: 1,
+			Minor: 0,
+			Patch: 0,
+			Name:  "drmtest",
+			Date:  "20170101",
+			Desc:  "fake drm card",
+		},
+		Caps: map[uint64]uint64{
+			drm.CapDumbBuffer:         1,
+			drm.CapPrime:              drm.PrimeCapImport,
+			drm.CapDumbPreferredDepth: 24,
+			drm.CapDumbPreferShadow:   1,
+			drm.CapTimestampMonotonic: 1,
+			drm.CapCursorWidth:        64,
+			drm.CapCursorHeight:       64,
+		},
+		ClientCaps: map[uint64]bool{
+			drm.ClientCapStereo3D:            true,
+			drm.ClientCapUniversalPlanes:     true,
+			drm.ClientCapAtomic:              true,
+			drm.ClientCapAspectRatio:         true,
+			drm.ClientCapWritebackConnectors: true,
+		},
+		Framebuffers: make(map[uint32]*Framebuffer),
+		DumbBuffers:  make(map[uint32]*DumbBuffer),
+		Blobs:        make(map[uint32]*Blob),
+		nextID:       1,
+		nextHandle:   1,
+		injected:     make(map[uint32][]syscall.Errno),
+		pipes:        make(map[uintptr]*os.File),
+		flips:        make(map[uint32]*flip),
+		created:      time.Now(),
+
+		magics:        make(map[uint32]uintptr),
+		nextMagic:     0x5eed,
+		authenticated: make(map[uintptr]bool),
+
+		clientCaps: make(map[uintptr]map[uint64]uint64),
+	}
+	c.vblank = sync.NewCond(&c.Mutex)
+	return c
+}
+
+// ClientCap returns the value the client with the given file
+// descriptor set the client capability capid to.
+func (c *Card) ClientCap(fd uintptr, capid uint64) uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.clientCaps[fd][capid]
+}
+
+// Authenticated tells if the client with the given file descriptor
+// was authenticated by the DRM master, or is the master.
+func (c *Card) Authenticated(fd uintptr) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.authenticated[fd] || (c.hasMaster && c.master == fd)
+}
+
+// Inject makes the next requests with the given code fail with errnos,
+// one errno per request, before the card handles them again.
+func (c *Card) Inject(code uint32, errnos ...syscall.Errno) {
+	c.Lock()
+	defer c.Unlock()
+	c.injected[code] = append(c.injected[code], errnos...)
+}
+
+// Open returns a device issuing its requests to the card. The device
+// file is the read side of a pipe, so it has a valid descriptor, and
+// the events of the card are written to the other side. Like
+// the kernel, the first device opened while the card has no DRM master
+// becomes the master.
+func (c *Card) Open() (*drm.Device, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// not r.Fd(), that would make the reads of the device blocking
+	conn, err := r.SyscallConn()
+	if err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	var fd uintptr
+	if err := conn.Control(func(f uintptr) { fd = f }); err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	c.Lock()
+	c.pipes[fd] = w
+	if !c.hasMaster {
+		c.master = fd
+		c.hasMaster = true
+	}
+	c.Unlock()
+	dev, err := drm.NewDevice(r, drm.NodePrimary, c)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return dev, nil
+}
+
+func (c *Card) newID() uint32 {
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+func (c *Card) AddCrtc() *Crtc {
+	c.Lock()
+	defer c.Unlock()
+	crtc := &Crtc{
+		ID:        c.newID(),
+		GammaSize: 256,
+	}
+	c.attach(&crtc.Props, &crtc.PropValues, propActive, 0)
+	c.attach(&crtc.Props, &crtc.PropValues, propModeID, 0)
+	c.Crtcs = append(c.Crtcs, crtc)
+	return crtc
+}
+
+// AddEncoder adds an encoder driving any of the CRTCs selected by the
+// bitmask possibleCrtcs (bit n is the nth CRTC of the card).
+func (c *Card) AddEncoder(typ, possibleCrtcs uint32) *Encoder {
+	c.Lock()
+	defer c.Unlock()
+	encoder := &Encoder{
+		ID:            c.newID(),
+		Type:          typ,
+		PossibleCrtcs: possibleCrtcs,
+	}
+	c.Encoders = append(c.Encoders, encoder)
+	return encoder
+}
+
+// AddConnector adds a connector attached to the given encoders. The
+// connector is connected if it has at least one mode.
+func (c *Card) AddConnector(typ uint32, encoders []uint32, modes ...mode.Info) *Connector {
+	c.Lock()
+	defer c.Unlock()
+	conn := &Connector{
+		ID:         c.newID(),
+		Type:       typ,
+		Connection: mode.Disconnected,
+		Modes:      modes,
+		Encoders:   encoders,
+	}
+	for _, other := range c.Connectors {
+		if other.Type == typ {
+			conn.TypeID++
+		}
+	}
+	conn.TypeID++
+	c.attach(&conn.Props, &conn.PropValues, propCrtcID, 0)
+	if len(modes) > 0 {
+		conn.Connection = mode.Connected
+		conn.Width = uint32(modes[0].Hdisplay) * 264 / 1000 // ~96dpi
+		conn.Height = uint32(modes[0].Vdisplay) * 264 / 1000
+	}
+	c.Connectors = append(c.Connectors, conn)
+	return conn
+}
+
+// AddHead adds a CRTC, an encoder able to drive it and a connector
+// attached to the encoder, like a monitor plugged into its own output.
+func (c *Card) AddHead(modes ...mode.Info) *Connector {
+	c.AddCrtc()
+	c.Lock()
+	possible := uint32(1) << uint(len(c.Crtcs)-1)
+	c.Unlock()
+	encoder := c.AddEncoder(mode.EncoderTMDS, possible)
+	return c.AddConnector(mode.ConnectorHDMIA, []uint32{encoder.ID}, modes...)
+}
+
+// Mode returns a mode with the given resolution and refresh rate,
+// using the CVT reduced blanking timings.
+func Mode(width, height uint16, refresh uint32) mode.Info {
+	htotal := width + 160
+	vtotal := height + 32
+	info := mode.Info{
+		Clock:      uint32(htotal) * uint32(vtotal) * refresh / 1000,
+		Hdisplay:   width,
+		HsyncStart: width + 48,
+		HsyncEnd:   width + 80,
+		Htotal:     htotal,
+		Vdisplay:   height,
+		VsyncStart: height + 3,
+		VsyncEnd:   height + 8,
+		Vtotal:     vtotal,
+		Vrefresh:   refresh,
+		Flags:      mode.FlagPHSync | mode.FlagNVSync,
+		Type:       mode.TypeDriver,
+	}
+	copy(info.Name[:], itoa(uint32(width))+"x"+itoa(uint32(height)))
+	return info
+}
+
+func itoa(n uint32) string {
+	var buf [10]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+		if n == 0 {
+			return string(buf[i:])
+		}
+	}
+}
+
+// Do implements ioctl.Doer.
+func (c *Card) Do(fd, cmd, ptr uintptr) error {
+	if c.BeforeRequest != nil {
+		c.BeforeRequest(uint32(cmd))
+	}
+	if c.CollectGarbage {
+		runtime.GC()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if errnos := c.injected[uint32(cmd)]; len(errnos) > 0 {
+		c.injected[uint32(cmd)] = errnos[1:]
+		return errnos[0]
+	}
+
+	isMaster := c.hasMaster && c.master == fd
+	arg := userPtr(uint64(ptr))
+	switch uint32(cmd) {
+	case drm.IOCTLVersion:
+		return c.version((*sysVersion)(arg))
+	case drm.IOCTLGetCap:
+		return c.getCap((*sysGetCap)(arg))
+	case drm.IOCTLSetClientCap:
+		return c.setClientCap(fd, (*sysGetCap)(arg))
+	case drm.IOCTLSetMaster:
+		return c.setMaster(fd)
+	case drm.IOCTLDropMaster:
+		return c.dropMaster(fd)
+	case drm.IOCTLGetMagic:
+		return c.getMagic(fd, (*uint32)(arg))
+	case drm.IOCTLAuthMagic:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.authMagic(*(*uint32)(arg))
+	case drm.IOCTLWaitVBlank:
+		return c.waitVBlank(fd, (*sysWaitVBlank)(arg))
+	case drm.IOCTLCrtcGetSequence:
+		return c.getCrtcSequence((*sysCrtcGetSequence)(arg))
+	case drm.IOCTLCrtcQueueSequence:
+		return c.queueCrtcSequence(fd, (*sysCrtcQueueSequence)(arg))
+	case mode.IOCTLModeResources:
+		return c.getResources((*sysResources)(arg))
+	case mode.IOCTLModeGetConnector:
+		return c.getConnector((*sysGetConnector)(arg))
+	case mode.IOCTLModeGetEncoder:
+		return c.getEncoder((*sysGetEncoder)(arg))
+	case mode.IOCTLModeGetCrtc:
+		return c.getCrtc((*sysCrtc)(arg))
+	case mode.IOCTLModeSetCrtc:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.setCrtc((*sysCrtc)(arg))
+	case mode.IOCTLModeGetPlaneResources:
+		return c.getPlaneResources(fd, (*sysGetPlaneRes)(arg))
+	case mode.IOCTLModeGetPlane:
+		return c.getPlane((*sysGetPlane)(arg))
+	case mode.IOCTLModeSetPlane:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.setPlane((*sysSetPlane)(arg))
+	case mode.IOCTLModeObjGetProperties:
+		return c.objGetProperties(fd, (*sysObjGetProperties)(arg))
+	case mode.IOCTLModeAtomic:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.atomic(fd, (*sysAtomic)(arg))
+	case mode.IOCTLModePageFlip:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.pageFlip(fd, (*sysPageFlip)(arg))
+	case mode.IOCTLModeObjSetProperty:
+		if !isMaster {
+			return syscall.EACCES
+		}
+		return c.objSetProperty((*sysObjSetProperty)(arg))
+	case mode.IOCTLModeGetProperty:
+		return c.getProperty((*sysGetProperty)(arg))
+	case mode.IOCTLModeGetPropBlob:
+		return c.getBlob((*sysGetBlob)(arg))
+	case mode.IOCTLModeCreatePropBlob:
+		return c.createBlob(fd, (*sysCreateBlob)(arg))
+	case mode.IOCTLModeDestroyPropBlob:
+		return c.destroyBlob(fd, (*uint32)(arg))
+	case mode.IOCTLModeCreateDumb:
+		return c.createDumb((*sysCreateDumb)(arg))
+	case mode.IOCTLModeMapDumb:
+		return c.mapDumb((*sysMapDumb)(arg))
+	case mode.IOCTLModeDestroyDumb:
+		return c.destroyDumb((*uint32)(arg))
+	case mode.IOCTLModeAddFB:
+		return c.addFB((*sysFBCmd)(arg))
+	case mode.IOCTLModeAddFB2:
+		return c.addFB2((*sysFBCmd2)(arg))
+	case mode.IOCTLModeRmFB:
+		return c.rmFB((*uint32)(arg))
+	}
+	return syscall.EINVAL
+}
+
+// userPtr converts an address given by the library back to a pointer.
+// The library pins the memory behind the addresses it passes with
+// ioctl.Pins until the request returns, so the address stays valid
+// while the fake serves the request.
+func userPtr(addr uint64) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(addr))
+}
+
+// putString copies s to the user buffer of size *length and stores
+// the real length of s there, like the kernel does.
+func putString(addr uintptr, length *uint, s string) {
+	if addr != 0 && *length > 0 {
+		n := int(*length)
+		if n > len(s) {
+			n = len(s)
+		}
+		copy(unsafe.Slice((*byte)(userPtr(uint64(addr))), n), s)
+	}
+	*length = uint(len(s))
+}
+
+// putIDs copies as many ids as the user buffer of size *count holds
+// and stores the real number of ids there.
+func putIDs[T ~uint32](addr uint64, count *uint32, ids []T) {
+	if addr != 0 && *count > 0 {
+		n := int(*count)
+		if n > len(ids) {
+			n = len(ids)
+		}
+		copy(unsafe.Slice((*T)(userPtr(addr)), n), ids)
+	}
+	*count = uint32(len(ids))
+}
+
+func (c *Card) version(v *sysVersion) error {
+	v.major = c.Version.Major
+	v.minor = c.Version.Minor
+	v.patch = c.Version.Patch
+	putString(v.name, &v.namelen, c.Version.Name)
+	putString(v.date, &v.datelen, c.Version.Date)
+	putString(v.desc, &v.desclen, c.Version.Desc)
+	return nil
+}
+
+func (c *Card) getCap(cap *sysGetCap) error {
+	val, ok := c.Caps[cap.id]
+	if !ok {
+		return syscall.EINVAL
+	}
+	cap.val = val
+	return nil
+}
+
+func (c *Card) setClientCap(fd uintptr, cap *sysGetCap) error {
+	if !c.ClientCaps[cap.id] {
+		if cap.id > drm.ClientCapCursorPlaneHotspot {
+			return syscall.EINVAL
+		}
+		return syscall.EOPNOTSUPP
+	}
+	caps := c.clientCaps[fd]
+	if caps == nil {
+		caps = make(map[uint64]uint64)
+		c.clientCaps[fd] = caps
+	}
+	switch cap.id {
+	case drm.ClientCapAtomic:
+		if cap.val > 2 {
+			return syscall.EINVAL
+		}
+		caps[drm.ClientCapUniversalPlanes] = cap.val
+		caps[drm.ClientCapAspectRatio] = cap.val
+	case drm.ClientCapWritebackConnectors, drm.ClientCapCursorPlaneHotspot:
+		if cap.val > 1 || caps[drm.ClientCapAtomic] == 0 {
+			return syscall.EINVAL
+		}
+	default:
+		if cap.val > 1 {
+			return syscall.EINVAL
+		}
+	}
+	caps[cap.id] = cap.val
+	return nil
+}
+
+func (c *Card) setMaster(fd uintptr) error {
+	if c.hasMaster {
+		if c.master == fd {
+			return nil
+		}
+		return syscall.EBUSY
+	}
+	c.master = fd
+	c.hasMaster = true
+	return nil
+}
+
+func (c *Card) dropMaster(fd uintptr) error {
+	if !c.hasMaster || c.master != fd {
+		return syscall.EINVAL
+	}
+	c.hasMaster = false
+	return nil
+}
+
+func (c *Card) getMagic(fd uintptr, magic *uint32) error {
+	for m, owner := range c.magics {
+		if owner == fd {
+			*magic = m
+			return nil
+		}
+	}
+	*magic = c.nextMagic
+	c.magics[c.nextMagic] = fd
+	c.nextMagic++
+	return nil
+}
+
+func (c *Card) authMagic(magic uint32) error {
+	fd, ok := c.magics[magic]
+	if !ok {
+		return syscall.EINVAL
+	}
+	delete(c.magics, magic)
+	c.authenticated[fd] = true
+	return nil
+}
+
+func (c *Card) getResources(res *sysResources) error {
+	var fbs, crtcs, encoders, connectors []uint32
+	for id := range c.Framebuffers {
+		fbs = append(fbs, id)
+	}
+	sort.Slice(fbs, func(i, j int) bool { return fbs[i] < fbs[j] })
+	for _, crtc := range c.Crtcs {
+		crtcs = append(crtcs, crtc.ID)
+	}
+	for _, encoder := range c.Encoders {
+		encoders = append(encoders, encoder.ID)
+	}
+	for _, conn := range c.Connectors {
+		connectors = append(connectors, conn.ID)
+	}
+	putIDs(res.fbIDPtr, &res.countFbs, fbs)
+	putIDs(res.crtcIDPtr, &res.countCrtcs, crtcs)
+	putIDs(res.connectorIDPtr, &res.countConnectors, connectors)
+	putIDs(res.encoderIDPtr, &res.countEncoders, encoders)
+	res.minWidth, res.maxWidth = 0, 8192
+	res.minHeight, res.maxHeight = 0, 8192
+	return nil
+}
+
+func (c *Card) connector(id uint32) *Connector {
+	for _, conn := range c.Connectors {
+		if conn.ID == id {
+			return conn
+		}
+	}
+	return nil
+}
+
+func (c *Card) encoder(id uint32) *Encoder {
+	for _, encoder := range c.Encoders {
+		if encoder.ID == id {
+			return encoder
+		}
+	}
+	return nil
+}
+
+func (c *Card) crtc(id uint32) *Crtc {
+	for _, crtc := range c.Crtcs {
+		if crtc.ID == id {
+			return crtc
+		}
+	}
+	return nil
+}
+
+func (c *Card) getConnector(req *sysGetConnector) error {
+	conn := c.connector(req.connectorID)
+	if conn == nil {
+		return syscall.ENOENT
+	}
+
+	// modes and encoders are copied only if all of them fit, while
+	// properties are copied up to the user buffer size.
+	nmodes := uint32(len(conn.Modes))
+	if req.countModes >= nmodes && nmodes > 0 && req.modesPtr != 0 {
+		copy(unsafe.Slice((*mode.Info)(userPtr(req.modesPtr)), nmodes), conn.Modes)
+	}
+	req.countModes = nmodes
+
+	nencoders := uint32(len(conn.Encoders))
+	if req.countEncoders >= nencoders && nencoders > 0 && req.encodersPtr != 0 {
+		copy(unsafe.Slice((*uint32)(userPtr(req.encodersPtr)), nencoders), conn.Encoders)
+	}
+	req.countEncoders = nencoders
+
+	nprops := uint32(len(conn.Props))
+	if req.countProps > 0 && req.propsPtr != 0 && req.propValuesPtr != 0 {
+		n := req.countProps
+		if n > nprops {
+			n = nprops
+		}
+		copy(unsafe.Slice((*uint32)(userPtr(req.propsPtr)), n), conn.Props)
+		copy(unsafe.Slice((*uint64)(userPtr(req.propValuesPtr)), n), conn.PropValues)
+	}
+	req.countProps = nprops
+
+	req.encoderID = conn.EncoderID
+	req.connectorType = conn.Type
+	req.connectorTypeID = conn.TypeID
+	req.connection = conn.Connection
+	req.mmWidth = conn.Width
+	req.mmHeight = conn.Height
+	req.subpixel = conn.Subpixel
+	return nil
+}
+
+func (c *Card) getEncoder(req *sysGetEncoder) error {
+	encoder := c.encoder(req.id)
+	if encoder == nil {
+		return syscall.ENOENT
+	}
+	req.typ = encoder.Type
+	req.crtcID = encoder.CrtcID
+	req.possibleCrtcs = encoder.PossibleCrtcs
+	req.possibleClones = encoder.PossibleClones
+	return nil
+}
+
+func (c *Card) getCrtc(req *sysCrtc) error {
+	crtc := c.crtc(req.id)
+	if crtc == nil {
+		return syscall.ENOENT
+	}
+	req.fbID = crtc.FbID
+	req.x = crtc.X
+	req.y = crtc.Y
+	req.gammaSize = crtc.GammaSize
+	req.modeValid = 0
+	req.mode = mode.Info{}
+	if crtc.Mode != nil {
+		req.modeValid = 1
+		req.mode = *crtc.Mode
+	}
+	return nil
+}
+
+func (c *Card) setCrtc(req *sysCrtc) error {
+	crtc := c.crtc(req.id)
+	if crtc == nil {
+		return syscall.ENOENT
+	}
+	if req.modeValid == 0 {
+		// disabling the CRTC
+		c.disableCrtc(crtc)
+		return nil
+	}
+	fb, ok := c.Framebuffers[req.fbID]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if req.x+uint32(req.mode.Hdisplay) > fb.Width ||
+		req.y+uint32(req.mode.Vdisplay) > fb.Height {
+		return syscall.ENOSPC
+	}
+
+	var conns []uint32
+	if req.countConnectors > 0 {
+		if req.setConnectorsPtr == 0 {
+			return syscall.EFAULT
+		}
+		ids := unsafe.Slice((*uint32)(userPtr(req.setConnectorsPtr)), req.countConnectors)
+		for _, id := range ids {
+			if c.connector(id) == nil {
+				return syscall.ENOENT
+			}
+		}
+		conns = append(conns, ids...)
+	}
+
+	m := req.mode
+	crtc.FbID = req.fbID
+	crtc.X = req.x
+	crtc.Y = req.y
+	crtc.Mode = &m
+	crtc.ModeBlob = c.modeBlob(&m)
+	crtc.Active = true
+	c.releaseBlobs()
+	for _, other := range c.Crtcs {
+		other.Connectors = without(other.Connectors, conns)
+	}
+	crtc.Connectors = conns
+	if primary := c.plane(crtc.Primary); primary != nil {
+		primary.FbID = req.fbID
+		primary.CrtcID = crtc.ID
+		primary.Src = mode.FixedRect{
+			X: mode.Fixed16Int(int(req.x)),
+			Y: mode.Fixed16Int(int(req.y)),
+			W: mode.Fixed16Int(int(m.Hdisplay)),
+			H: mode.Fixed16Int(int(m.Vdisplay)),
+		}
+		primary.Dst = image.Rect(0, 0, int(m.Hdisplay), int(m.Vdisplay))
+	}
+
+	// route the first encoder of each connector to this CRTC
+	for _, id := range conns {
+		conn := c.connector(id)
+		if len(conn.Encoders) == 0 {
+			continue
+		}
+		conn.EncoderID = conn.Encoders[0]
+		if encoder := c.encoder(conn.EncoderID); encoder != nil {
+			encoder.CrtcID = crtc.ID
+		}
+	}
+	return nil
+}
+
+func (c *Card) createDumb(req *sysCreateDumb) error {
+	if req.width == 0 || req.height == 0 || req.bpp == 0 {
+		return syscall.EINVAL
+	}
+	pitch := req.width * ((req.bpp + 7) / 8)
+	pitch = (pitch + 63) &^ 63
+	dumb := &DumbBuffer{
+		Handle: c.nextHandle,
+		Width:  req.width,
+		Height: req.height,
+		BPP:    req.bpp,
+		Pitch:  pitch,
+		Size:   uint64(pitch) * uint64(req.height),
+	}
+	c.nextHandle++
+	c.DumbBuffers[dumb.Handle] = dumb
+	req.handle = dumb.Handle
+	req.pitch = dumb.Pitch
+	req.size = dumb.Size
+	return nil
+}
+
+func (c *Card) mapDumb(req *sysMapDumb) error {
+	if _, ok := c.DumbBuffers[req.handle]; !ok {
+		return syscall.ENOENT
+	}
+	req.offset = uint64(req.handle) << 32
+	return nil
+}
+
+func (c *Card) destroyDumb(handle *uint32) error {
+	if _, ok := c.DumbBuffers[*handle]; !ok {
+		return syscall.ENOENT
+	}
+	delete(c.DumbBuffers, *handle)
+	return nil
+}
+
+func (c *Card) addFB(req *sysFBCmd) error {
+	dumb, ok := c.DumbBuffers[req.handle]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if uint64(req.pitch)*uint64(req.height) > dumb.Size ||
+		req.width*((req.bpp+7)/8) > req.pitch {
+		return syscall.EINVAL
+	}
+	fb := &Framebuffer{
+		ID:     c.newID(),
+		Width:  req.width,
+		Height: req.height,
+		Pitch:  req.pitch,
+		BPP:    req.bpp,
+		Depth:  req.depth,
+		Format: fourcc.FromLegacy(req.bpp, req.depth),
+		Handle: req.handle,
+	}
+	fb.Handles[0], fb.Pitches[0] = req.handle, req.pitch
+	c.Framebuffers[fb.ID] = fb
+	req.fbID = fb.ID
+	return nil
+}
+
+func (c *Card) addFB2(req *sysFBCmd2) error {
+	if req.flags&^(mode.FBInterlaced|mode.FBModifiers) != 0 ||
+		(req.flags&mode.FBModifiers != 0 && c.Caps[drm.CapAddFB2Modifiers] == 0) ||
+		req.width == 0 || req.height == 0 || req.handles[0] == 0 {
+		return syscall.EINVAL
+	}
+	for i, handle := range req.handles {
+		if handle == 0 {
+			if req.pitches[i] != 0 || req.offsets[i] != 0 || req.modifiers[i] != 0 {
+				return syscall.EINVAL
+			}
+			continue
+		}
+		dumb, ok := c.DumbBuffers[handle]
+		if !ok {
+			return syscall.ENOENT
+		}
+		// only the first plane is known to be as high as the buffer
+		height := uint64(1)
+		if i == 0 {
+			height = uint64(req.height)
+		}
+		if req.pitches[i] == 0 ||
+			uint64(req.offsets[i])+uint64(req.pitches[i])*height > dumb.Size {
+			return syscall.EINVAL
+		}
+	}
+	fb := &Framebuffer{
+		ID:       c.newID(),
+		Width:    req.width,
+		Height:   req.height,
+		Pitch:    req.pitches[0],
+		Format:   fourcc.Format(req.pixelFormat),
+		Handle:   req.handles[0],
+		Flags:    req.flags,
+		Handles:  req.handles,
+		Pitches:  req.pitches,
+		Offsets:  req.offsets,
+		Modifier: fourcc.Modifier(req.modifiers[0]),
+	}
+	c.Framebuffers[fb.ID] = fb
+	req.fbID = fb.ID
+	return nil
+}
+
+func (c *Card) rmFB(id *uint32) error {
+	if _, ok := c.Framebuffers[*id]; !ok {
+		return syscall.ENOENT
+	}
+	delete(c.Framebuffers, *id)
+	for _, crtc := range c.Crtcs {
+		if crtc.FbID == *id {
+			c.disableCrtc(crtc)
+		}
+	}
+	for _, plane := range c.Planes {
+		if plane.FbID == *id {
+			plane.FbID = 0
+			plane.CrtcID = 0
+		}
+	}
+	return nil
+}
+
+func (c *Card) disableCrtc(crtc *Crtc) {
+	crtc.FbID = 0
+	crtc.Mode = nil
+	crtc.ModeBlob = 0
+	crtc.Active = false
+	crtc.Connectors = nil
+	c.releaseBlobs()
+	for _, plane := range c.Planes {
+		if plane.CrtcID == crtc.ID {
+			plane.FbID = 0
+			plane.CrtcID = 0
+		}
+	}
+}
+
+// without returns ids without the elements of remove.
+func without(ids, remove []uint32) []uint32 {
+	var ret []uint32
+next:
+	for _, id := range ids {
+		for _, r := range remove {
+			if id == r {
+				continue next
+			}
+		}
+		ret = append(ret, id)
+	}
+	return ret
+}
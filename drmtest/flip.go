@@ -0,0 +1,97 @@
+package drmtest
+
+import (
+	"syscall"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// flip is a page flip, or atomic commit, waiting for a vblank.
+type flip struct {
+	fd       uintptr
+	fbID     uint32 // zero for atomic commits, already applied
+	event    bool
+	userData uint64
+	target   uint64 // vblank sequence
+}
+
+const pageFlipFlags = mode.PageFlipEvent | mode.PageFlipAsync |
+	mode.PageFlipTargetAbsolute | mode.PageFlipTargetRelative
+
+func (c *Card) pageFlip(fd uintptr, req *sysPageFlip) error {
+	target := req.flags & (mode.PageFlipTargetAbsolute | mode.PageFlipTargetRelative)
+	if req.flags&^pageFlipFlags != 0 ||
+		target == mode.PageFlipTargetAbsolute|mode.PageFlipTargetRelative ||
+		(target == 0 && req.sequence != 0) ||
+		(target != 0 && c.Caps[drm.CapPageFlipTarget] == 0) ||
+		(req.flags&mode.PageFlipAsync != 0 && c.Caps[drm.CapAsyncPageFlip] == 0) {
+		return syscall.EINVAL
+	}
+	crtc := c.crtc(req.crtcID)
+	if crtc == nil {
+		return syscall.ENOENT
+	}
+	fb, ok := c.Framebuffers[req.fbID]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if !crtc.Active || crtc.Mode == nil {
+		return syscall.EINVAL
+	}
+	if crtc.X+uint32(crtc.Mode.Hdisplay) > fb.Width ||
+		crtc.Y+uint32(crtc.Mode.Vdisplay) > fb.Height {
+		return syscall.ENOSPC
+	}
+	if _, ok := c.flips[crtc.ID]; ok {
+		return syscall.EBUSY
+	}
+
+	next := crtc.Sequence + 1
+	switch target {
+	case mode.PageFlipTargetAbsolute:
+		next = uint64(req.sequence)
+	case mode.PageFlipTargetRelative:
+		next = crtc.Sequence + uint64(req.sequence)
+	}
+	// like the kernel, no flip further than the next vblank
+	if next > crtc.Sequence+1 {
+		return syscall.EINVAL
+	}
+	c.flips[crtc.ID] = &flip{
+		fd:       fd,
+		fbID:     fb.ID,
+		event:    req.flags&mode.PageFlipEvent != 0,
+		userData: req.userData,
+		target:   next,
+	}
+	return nil
+}
+
+// atomicFlips returns the CRTCs an atomic commit of the objects objs
+// changes, failing if a flip is pending on any of them (the kernel
+// would wait for it unless the commit is non-blocking).
+func (c *Card) atomicFlips(state *atomicState, objs []uint32) ([]uint32, error) {
+	var crtcs []uint32
+	add := func(id uint32) {
+		if id != 0 && !contains(crtcs, id) {
+			crtcs = append(crtcs, id)
+		}
+	}
+	for _, id := range objs {
+		if _, ok := state.crtcs[id]; ok {
+			add(id)
+		} else if plane, ok := state.planes[id]; ok {
+			add(plane.crtc)
+			if old := c.plane(id); old != nil {
+				add(old.CrtcID)
+			}
+		}
+	}
+	for _, id := range crtcs {
+		if _, ok := c.flips[id]; ok {
+			return nil, syscall.EBUSY
+		}
+	}
+	return crtcs, nil
+}
@@ -0,0 +1,222 @@
+package drmtest
+
+import (
+	"encoding/binary"
+	"image"
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+type (
+	Plane struct {
+		ID            uint32
+		CrtcID, FbID  uint32
+		PossibleCrtcs uint32
+		GammaSize     uint32
+		Formats       []fourcc.Format
+
+		Src mode.FixedRect
+		Dst image.Rectangle
+
+		Props      []uint32
+		PropValues []uint64
+	}
+)
+
+// AddPlane adds a plane of type typ (mode.PlaneOverlay, PlanePrimary
+// or PlaneCursor) able to scan out from any of the CRTCs selected by
+// the bitmask possibleCrtcs, in the given formats. A primary
+// plane becomes the primary plane of the first possible CRTC without
+// one.
+func (c *Card) AddPlane(typ uint64, possibleCrtcs uint32, formats ...fourcc.Format) *Plane {
+	c.Lock()
+	defer c.Unlock()
+	plane := &Plane{
+		ID:            c.newID(),
+		PossibleCrtcs: possibleCrtcs,
+		Formats:       formats,
+	}
+	c.attach(&plane.Props, &plane.PropValues, propPlaneType, typ)
+	c.attach(&plane.Props, &plane.PropValues, propFbID, 0)
+	c.attach(&plane.Props, &plane.PropValues, propCrtcID, 0)
+	for _, prop := range planeRectProps() {
+		c.attach(&plane.Props, &plane.PropValues, prop, 0)
+	}
+	c.Planes = append(c.Planes, plane)
+
+	if typ == mode.PlanePrimary {
+		for i, crtc := range c.Crtcs {
+			if possibleCrtcs&(1<<uint(i)) != 0 && crtc.Primary == 0 {
+				crtc.Primary = plane.ID
+				break
+			}
+		}
+	}
+	return plane
+}
+
+// AddInFormats attaches to the plane an IN_FORMATS blob advertising
+// the modifiers, in order, with the formats of the plane supported
+// says they can be used with, all of them if nil. The blob is laid out
+// as the kernel does.
+func (c *Card) AddInFormats(plane *Plane, modifiers []fourcc.Modifier,
+	supported func(format fourcc.Format, modifier fourcc.Modifier) bool) *Blob {
+	hdrSize := int(unsafe.Sizeof(sysFormatModifierBlob{}))
+	modSize := int(unsafe.Sizeof(sysFormatModifier{}))
+	modsOffset := (hdrSize + 4*len(plane.Formats) + 7) &^ 7
+
+	var mods []sysFormatModifier
+	for _, modifier := range modifiers {
+		for offset := 0; offset < len(plane.Formats); offset += 64 {
+			mod := sysFormatModifier{
+				offset:   uint32(offset),
+				modifier: uint64(modifier),
+			}
+			for i := offset; i < len(plane.Formats) && i < offset+64; i++ {
+				if supported == nil || supported(plane.Formats[i], modifier) {
+					mod.formats |= 1 << uint(i-offset)
+				}
+			}
+			if mod.formats != 0 {
+				mods = append(mods, mod)
+			}
+		}
+	}
+
+	data := make([]byte, modsOffset+modSize*len(mods))
+	ne := binary.NativeEndian
+	ne.PutUint32(data[0:], 1) // FORMAT_BLOB_CURRENT
+	ne.PutUint32(data[8:], uint32(len(plane.Formats)))
+	ne.PutUint32(data[12:], uint32(hdrSize))
+	ne.PutUint32(data[16:], uint32(len(mods)))
+	ne.PutUint32(data[20:], uint32(modsOffset))
+	for i, format := range plane.Formats {
+		ne.PutUint32(data[hdrSize+4*i:], uint32(format))
+	}
+	for i, mod := range mods {
+		entry := data[modsOffset+modSize*i:]
+		ne.PutUint64(entry[0:], mod.formats)
+		ne.PutUint32(entry[8:], mod.offset)
+		ne.PutUint64(entry[16:], mod.modifier)
+	}
+
+	blob := c.AddBlob(data)
+	c.Lock()
+	defer c.Unlock()
+	c.attach(&plane.Props, &plane.PropValues, propInFormats, uint64(blob.ID))
+	return blob
+}
+
+// attach attaches the standard property tmpl to an object.
+func (c *Card) attach(props *[]uint32, values *[]uint64, tmpl Property, value uint64) {
+	prop := c.property(tmpl)
+	*props = append(*props, prop.ID)
+	*values = append(*values, value)
+}
+
+func (c *Card) crtcIndex(id uint32) int {
+	for i, crtc := range c.Crtcs {
+		if crtc.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Card) plane(id uint32) *Plane {
+	for _, plane := range c.Planes {
+		if plane.ID == id {
+			return plane
+		}
+	}
+	return nil
+}
+
+func (c *Card) getPlaneResources(fd uintptr, res *sysGetPlaneRes) error {
+	universal := c.clientCaps[fd][drm.ClientCapUniversalPlanes] != 0
+	var planes []uint32
+	for _, plane := range c.Planes {
+		typ, _ := c.propValue(plane.Props, plane.PropValues, "type")
+		if typ != mode.PlaneOverlay && !universal {
+			continue
+		}
+		planes = append(planes, plane.ID)
+	}
+	// like the kernel, ids are copied only if all of them fit
+	if res.countPlanes >= uint32(len(planes)) {
+		putIDs(res.planeIDPtr, &res.countPlanes, planes)
+	}
+	res.countPlanes = uint32(len(planes))
+	return nil
+}
+
+func (c *Card) getPlane(req *sysGetPlane) error {
+	plane := c.plane(req.id)
+	if plane == nil {
+		return syscall.ENOENT
+	}
+	req.crtcID = plane.CrtcID
+	req.fbID = plane.FbID
+	req.possibleCrtcs = plane.PossibleCrtcs
+	req.gammaSize = plane.GammaSize
+	if req.countFormatTypes >= uint32(len(plane.Formats)) {
+		putIDs(req.formatTypePtr, &req.countFormatTypes, plane.Formats)
+	}
+	req.countFormatTypes = uint32(len(plane.Formats))
+	return nil
+}
+
+func (c *Card) setPlane(req *sysSetPlane) error {
+	plane := c.plane(req.planeID)
+	if plane == nil {
+		return syscall.ENOENT
+	}
+	if req.fbID == 0 {
+		plane.CrtcID = 0
+		plane.FbID = 0
+		plane.Src = mode.FixedRect{}
+		plane.Dst = image.Rectangle{}
+		return nil
+	}
+
+	crtcIndex := c.crtcIndex(req.crtcID)
+	fb, ok := c.Framebuffers[req.fbID]
+	if crtcIndex < 0 || !ok {
+		return syscall.ENOENT
+	}
+	if plane.PossibleCrtcs&(1<<uint(crtcIndex)) == 0 {
+		return syscall.EINVAL
+	}
+	supported := false
+	for _, format := range plane.Formats {
+		supported = supported || format == fb.Format
+	}
+	if !supported {
+		return syscall.EINVAL
+	}
+	if int32(req.crtcW) < 0 || int32(req.crtcH) < 0 ||
+		req.crtcX > int32(0x7fffffff-req.crtcW) ||
+		req.crtcY > int32(0x7fffffff-req.crtcH) {
+		return syscall.ERANGE
+	}
+	width, height := uint64(fb.Width)<<16, uint64(fb.Height)<<16
+	if uint64(req.srcW) > width || uint64(req.srcX) > width-uint64(req.srcW) ||
+		uint64(req.srcH) > height || uint64(req.srcY) > height-uint64(req.srcH) {
+		return syscall.ENOSPC
+	}
+	plane.CrtcID = req.crtcID
+	plane.FbID = req.fbID
+	plane.Src = mode.FixedRect{
+		X: mode.Fixed16(req.srcX),
+		Y: mode.Fixed16(req.srcY),
+		W: mode.Fixed16(req.srcW),
+		H: mode.Fixed16(req.srcH),
+	}
+	plane.Dst = image.Rect(int(req.crtcX), int(req.crtcY),
+		int(req.crtcX)+int(req.crtcW), int(req.crtcY)+int(req.crtcH))
+	return nil
+}
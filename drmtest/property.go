@@ -0,0 +1,368 @@
+package drmtest
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+type (
+
+	// Property is a property of the mode-setting objects.
+	Property struct {
+		ID     uint32
+		Name   string
+		Flags  uint32
+		Values []uint64
+		Enums  []mode.PropertyEnum
+	}
+)
+
+// AddProperty adds prop to the card, giving it a new id.
+func (c *Card) AddProperty(prop *Property) *Property {
+	c.Lock()
+	defer c.Unlock()
+	prop.ID = c.newID()
+	c.Properties = append(c.Properties, prop)
+	return prop
+}
+
+// Attach attaches prop, with the initial value, to the connector, CRTC
+// or plane objID.
+func (c *Card) Attach(objID uint32, prop *Property, value uint64) {
+	c.Lock()
+	defer c.Unlock()
+	switch {
+	case c.connector(objID) != nil:
+		conn := c.connector(objID)
+		conn.Props = append(conn.Props, prop.ID)
+		conn.PropValues = append(conn.PropValues, value)
+	case c.crtc(objID) != nil:
+		crtc := c.crtc(objID)
+		crtc.Props = append(crtc.Props, prop.ID)
+		crtc.PropValues = append(crtc.PropValues, value)
+	case c.plane(objID) != nil:
+		plane := c.plane(objID)
+		plane.Props = append(plane.Props, prop.ID)
+		plane.PropValues = append(plane.PropValues, value)
+	default:
+		panic("drmtest: no object " + itoa(objID))
+	}
+}
+
+// PropValue returns the current value of prop on the connector, CRTC
+// or plane objID, as the requests report it, or false if the property
+// is not attached to the object.
+func (c *Card) PropValue(objID uint32, prop *Property) (uint64, bool) {
+	c.Lock()
+	defer c.Unlock()
+	props, values, ok := c.objectProps(objID, mode.ObjectAny)
+	if !ok {
+		return 0, false
+	}
+	for i, id := range props {
+		if id == prop.ID {
+			return c.currentValue(objID, prop, values[i]), true
+		}
+	}
+	return 0, false
+}
+
+// property returns the property called name, creating it if needed.
+func (c *Card) property(tmpl Property) *Property {
+	for _, prop := range c.Properties {
+		if prop.Name == tmpl.Name && prop.Flags == tmpl.Flags {
+			return prop
+		}
+	}
+	prop := &tmpl
+	prop.ID = c.newID()
+	c.Properties = append(c.Properties, prop)
+	return prop
+}
+
+// Standard properties of the atomic mode-setting objects
+var (
+	propActive = Property{
+		Name:   "ACTIVE",
+		Flags:  mode.PropRange | mode.PropAtomic,
+		Values: []uint64{0, 1},
+	}
+	propModeID = Property{
+		Name:  "MODE_ID",
+		Flags: mode.PropBlob | mode.PropAtomic,
+	}
+	propCrtcID = Property{
+		Name:   "CRTC_ID",
+		Flags:  mode.PropObject | mode.PropAtomic,
+		Values: []uint64{mode.ObjectCrtc},
+	}
+	propFbID = Property{
+		Name:   "FB_ID",
+		Flags:  mode.PropObject | mode.PropAtomic,
+		Values: []uint64{mode.ObjectFB},
+	}
+	propInFormats = Property{
+		Name:  "IN_FORMATS",
+		Flags: mode.PropBlob | mode.PropImmutable,
+	}
+	propPlaneType = Property{
+		Name:   "type",
+		Flags:  mode.PropEnum | mode.PropImmutable,
+		Values: []uint64{mode.PlaneOverlay, mode.PlanePrimary, mode.PlaneCursor},
+		Enums: []mode.PropertyEnum{
+			{Value: mode.PlaneOverlay, Name: "Overlay"},
+			{Value: mode.PlanePrimary, Name: "Primary"},
+			{Value: mode.PlaneCursor, Name: "Cursor"},
+		},
+	}
+)
+
+// planeRectProps are the standard properties of the plane source and
+// destination rectangles.
+func planeRectProps() []Property {
+	var props []Property
+	for _, name := range []string{"SRC_X", "SRC_Y", "SRC_W", "SRC_H"} {
+		props = append(props, Property{
+			Name:   name,
+			Flags:  mode.PropRange | mode.PropAtomic,
+			Values: []uint64{0, 0xffffffff},
+		})
+	}
+	for _, name := range []string{"CRTC_X", "CRTC_Y"} {
+		props = append(props, Property{
+			Name:   name,
+			Flags:  mode.PropSignedRange | mode.PropAtomic,
+			Values: []uint64{uint64(1<<64 - 1<<31), 1<<31 - 1},
+		})
+	}
+	for _, name := range []string{"CRTC_W", "CRTC_H"} {
+		props = append(props, Property{
+			Name:   name,
+			Flags:  mode.PropRange | mode.PropAtomic,
+			Values: []uint64{0, 1<<31 - 1},
+		})
+	}
+	return props
+}
+
+// currentValue returns the value of prop on the object id. The values
+// of the standard atomic properties follow the state of the object,
+// whether it was changed by legacy or atomic requests.
+func (c *Card) currentValue(id uint32, prop *Property, stored uint64) uint64 {
+	b2u := func(b bool) uint64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	if crtc := c.crtc(id); crtc != nil {
+		switch prop.Name {
+		case "ACTIVE":
+			return b2u(crtc.Active)
+		case "MODE_ID":
+			return uint64(crtc.ModeBlob)
+		}
+	}
+	if conn := c.connector(id); conn != nil && prop.Name == "CRTC_ID" {
+		return uint64(c.connectorCrtc(conn.ID))
+	}
+	if plane := c.plane(id); plane != nil {
+		switch prop.Name {
+		case "FB_ID":
+			return uint64(plane.FbID)
+		case "CRTC_ID":
+			return uint64(plane.CrtcID)
+		case "SRC_X":
+			return uint64(plane.Src.X)
+		case "SRC_Y":
+			return uint64(plane.Src.Y)
+		case "SRC_W":
+			return uint64(plane.Src.W)
+		case "SRC_H":
+			return uint64(plane.Src.H)
+		case "CRTC_X":
+			return uint64(int64(plane.Dst.Min.X))
+		case "CRTC_Y":
+			return uint64(int64(plane.Dst.Min.Y))
+		case "CRTC_W":
+			return uint64(plane.Dst.Dx())
+		case "CRTC_H":
+			return uint64(plane.Dst.Dy())
+		}
+	}
+	return stored
+}
+
+// connectorCrtc returns the CRTC driving the connector, or zero.
+func (c *Card) connectorCrtc(id uint32) uint32 {
+	for _, crtc := range c.Crtcs {
+		for _, conn := range crtc.Connectors {
+			if conn == id {
+				return crtc.ID
+			}
+		}
+	}
+	return 0
+}
+
+// propValue returns the value of the property called name in props.
+func (c *Card) propValue(props []uint32, values []uint64, name string) (uint64, bool) {
+	for i, id := range props {
+		for _, prop := range c.Properties {
+			if prop.ID == id && prop.Name == name {
+				return values[i], true
+			}
+		}
+	}
+	return 0, false
+}
+
+// objectProps returns the properties of the object id of type typ.
+func (c *Card) objectProps(id, typ uint32) ([]uint32, []uint64, bool) {
+	if typ == mode.ObjectConnector || typ == mode.ObjectAny {
+		if conn := c.connector(id); conn != nil {
+			return conn.Props, conn.PropValues, true
+		}
+	}
+	if typ == mode.ObjectCrtc || typ == mode.ObjectAny {
+		if crtc := c.crtc(id); crtc != nil {
+			return crtc.Props, crtc.PropValues, true
+		}
+	}
+	if typ == mode.ObjectPlane || typ == mode.ObjectAny {
+		if plane := c.plane(id); plane != nil {
+			return plane.Props, plane.PropValues, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (c *Card) objGetProperties(fd uintptr, req *sysObjGetProperties) error {
+	objProps, objValues, ok := c.objectProps(req.objID, req.objType)
+	if !ok {
+		return syscall.ENOENT
+	}
+
+	// atomic properties are hidden from the legacy clients
+	atomic := c.clientCaps[fd][drm.ClientCapAtomic] != 0
+	var (
+		props  []uint32
+		values []uint64
+	)
+	for i, id := range objProps {
+		prop := c.propertyByID(id)
+		if prop != nil && prop.Flags&mode.PropAtomic != 0 && !atomic {
+			continue
+		}
+		value := objValues[i]
+		if prop != nil {
+			value = c.currentValue(req.objID, prop, value)
+		}
+		props = append(props, id)
+		values = append(values, value)
+	}
+	n := uint32(len(props))
+	if req.countProps >= n && n > 0 {
+		copy(unsafe.Slice((*uint32)(userPtr(req.propsPtr)), n), props)
+		copy(unsafe.Slice((*uint64)(userPtr(req.propValuesPtr)), n), values)
+	}
+	req.countProps = n
+	return nil
+}
+
+func (c *Card) propertyByID(id uint32) *Property {
+	for _, prop := range c.Properties {
+		if prop.ID == id {
+			return prop
+		}
+	}
+	return nil
+}
+
+func (c *Card) getProperty(req *sysGetProperty) error {
+	prop := c.propertyByID(req.id)
+	if prop == nil {
+		return syscall.ENOENT
+	}
+	req.flags = prop.Flags
+	req.name = [mode.PropNameLen]uint8{}
+	copy(req.name[:mode.PropNameLen-1], prop.Name)
+
+	// values and enums are copied only if all of them fit
+	n := uint32(len(prop.Values))
+	if req.countValues >= n && n > 0 {
+		copy(unsafe.Slice((*uint64)(userPtr(req.valuesPtr)), n), prop.Values)
+	}
+	req.countValues = n
+
+	if prop.Flags&(mode.PropEnum|mode.PropBitmask) == 0 {
+		req.countEnumBlobs = 0
+		return nil
+	}
+	n = uint32(len(prop.Enums))
+	if req.countEnumBlobs >= n && n > 0 {
+		enums := unsafe.Slice((*sysPropertyEnum)(userPtr(req.enumBlobPtr)), n)
+		for i, enum := range prop.Enums {
+			enums[i].value = enum.Value
+			enums[i].name = [mode.PropNameLen]uint8{}
+			copy(enums[i].name[:mode.PropNameLen-1], enum.Name)
+		}
+	}
+	req.countEnumBlobs = n
+	return nil
+}
+
+func (c *Card) objSetProperty(req *sysObjSetProperty) error {
+	props, values, ok := c.objectProps(req.objID, req.objType)
+	if !ok {
+		return syscall.ENOENT
+	}
+	prop := c.propertyByID(req.propID)
+	if prop == nil {
+		return syscall.ENOENT
+	}
+	for i, id := range props {
+		if id != prop.ID {
+			continue
+		}
+		if !c.validValue(prop, req.value) {
+			return syscall.EINVAL
+		}
+		values[i] = req.value
+		return nil
+	}
+	// not attached to the object
+	return syscall.EINVAL
+}
+
+// validValue tells if the property can be changed to value, like
+// drm_property_change_valid_get does.
+func (c *Card) validValue(prop *Property, value uint64) bool {
+	if prop.Flags&mode.PropImmutable != 0 {
+		return false
+	}
+	switch {
+	case prop.Flags&mode.PropRange != 0:
+		return value >= prop.Values[0] && value <= prop.Values[1]
+	case prop.Flags&mode.PropExtendedType == mode.PropSignedRange:
+		v := int64(value)
+		return v >= int64(prop.Values[0]) && v <= int64(prop.Values[1])
+	case prop.Flags&mode.PropBitmask != 0:
+		var valid uint64
+		for _, enum := range prop.Enums {
+			valid |= 1 << enum.Value
+		}
+		return value&^valid == 0
+	case prop.Flags&mode.PropEnum != 0:
+		for _, enum := range prop.Enums {
+			if enum.Value == value {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,255 @@
+package drmtest
+
+import "github.com/NeowayLabs/drm/mode"
+
+// Kernel structures, as the drm and mode packages lay them out.
+type (
+	// struct drm_version: the lengths are size_t and the strings
+	// pointers, as wide as the architecture
+	sysVersion struct {
+		major   int32
+		minor   int32
+		patch   int32
+		namelen uint
+		name    uintptr
+		datelen uint
+		date    uintptr
+		desclen uint
+		desc    uintptr
+	}
+
+	sysGetCap struct {
+		id  uint64
+		val uint64
+	}
+
+	sysResources struct {
+		fbIDPtr              uint64
+		crtcIDPtr            uint64
+		connectorIDPtr       uint64
+		encoderIDPtr         uint64
+		countFbs             uint32
+		countCrtcs           uint32
+		countConnectors      uint32
+		countEncoders        uint32
+		minWidth, maxWidth   uint32
+		minHeight, maxHeight uint32
+	}
+
+	sysGetConnector struct {
+		encodersPtr   uint64
+		modesPtr      uint64
+		propsPtr      uint64
+		propValuesPtr uint64
+
+		countModes    uint32
+		countProps    uint32
+		countEncoders uint32
+
+		encoderID       uint32
+		connectorID     uint32
+		connectorType   uint32
+		connectorTypeID uint32
+
+		connection        uint32
+		mmWidth, mmHeight uint32
+		subpixel          uint32
+		pad               uint32
+	}
+
+	sysGetEncoder struct {
+		id             uint32
+		typ            uint32
+		crtcID         uint32
+		possibleCrtcs  uint32
+		possibleClones uint32
+	}
+
+	sysCrtc struct {
+		setConnectorsPtr uint64
+		countConnectors  uint32
+
+		id   uint32
+		fbID uint32
+
+		x, y uint32
+
+		gammaSize uint32
+		modeValid uint32
+		mode      mode.Info
+	}
+
+	sysCreateDumb struct {
+		height, width uint32
+		bpp           uint32
+		flags         uint32
+		handle        uint32
+		pitch         uint32
+		size          uint64
+	}
+
+	sysMapDumb struct {
+		handle uint32
+		pad    uint32
+		offset uint64
+	}
+
+	sysFBCmd struct {
+		fbID          uint32
+		width, height uint32
+		pitch         uint32
+		bpp           uint32
+		depth         uint32
+		handle        uint32
+	}
+
+	sysFBCmd2 struct {
+		fbID          uint32
+		width, height uint32
+		pixelFormat   uint32
+		flags         uint32
+		handles       [4]uint32
+		pitches       [4]uint32
+		offsets       [4]uint32
+		modifiers     [4]uint64
+	}
+
+	sysGetPlaneRes struct {
+		planeIDPtr  uint64
+		countPlanes uint32
+	}
+
+	sysGetPlane struct {
+		id               uint32
+		crtcID, fbID     uint32
+		possibleCrtcs    uint32
+		gammaSize        uint32
+		countFormatTypes uint32
+		formatTypePtr    uint64
+	}
+
+	sysSetPlane struct {
+		planeID, crtcID, fbID uint32
+		flags                 uint32
+		crtcX, crtcY          int32
+		crtcW, crtcH          uint32
+		srcX, srcY            uint32
+		srcH, srcW            uint32
+	}
+
+	sysPropertyEnum struct {
+		value uint64
+		name  [mode.PropNameLen]uint8
+	}
+
+	sysGetProperty struct {
+		valuesPtr      uint64
+		enumBlobPtr    uint64
+		id             uint32
+		flags          uint32
+		name           [mode.PropNameLen]uint8
+		countValues    uint32
+		countEnumBlobs uint32
+	}
+
+	sysObjGetProperties struct {
+		propsPtr      uint64
+		propValuesPtr uint64
+		countProps    uint32
+		objID         uint32
+		objType       uint32
+	}
+
+	sysObjSetProperty struct {
+		value   uint64
+		propID  uint32
+		objID   uint32
+		objType uint32
+	}
+
+	sysGetBlob struct {
+		id     uint32
+		length uint32
+		data   uint64
+	}
+
+	sysCreateBlob struct {
+		data   uint64
+		length uint32
+		id     uint32
+	}
+
+	sysAtomic struct {
+		flags         uint32
+		countObjs     uint32
+		objsPtr       uint64
+		countPropsPtr uint64
+		propsPtr      uint64
+		propValuesPtr uint64
+		reserved      uint64
+		userData      uint64
+	}
+
+	sysPageFlip struct {
+		crtcID   uint32
+		fbID     uint32
+		flags    uint32
+		sequence uint32
+		userData uint64
+	}
+
+	sysEventVBlank struct {
+		typ      uint32
+		length   uint32
+		userData uint64
+		tvSec    uint32
+		tvUsec   uint32
+		sequence uint32
+		crtcID   uint32
+	}
+
+	sysEventCrtcSequence struct {
+		typ      uint32
+		length   uint32
+		userData uint64
+		timeNs   int64
+		sequence uint64
+	}
+
+	sysWaitVBlank struct {
+		typ      uint32
+		sequence uint32
+		tvalSec  int // signal, the user data, in the request
+		tvalUsec int
+	}
+
+	sysCrtcGetSequence struct {
+		crtcID     uint32
+		active     uint32
+		sequence   uint64
+		sequenceNs int64
+	}
+
+	sysCrtcQueueSequence struct {
+		crtcID   uint32
+		flags    uint32
+		sequence uint64
+		userData uint64
+	}
+
+	sysFormatModifierBlob struct {
+		version         uint32
+		flags           uint32
+		countFormats    uint32
+		formatsOffset   uint32
+		countModifiers  uint32
+		modifiersOffset uint32
+	}
+
+	sysFormatModifier struct {
+		formats  uint64
+		offset   uint32
+		pad      uint32
+		modifier uint64
+	}
+)
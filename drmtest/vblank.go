@@ -0,0 +1,203 @@
+package drmtest
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm"
+)
+
+// vblankWait is an event queued for a vblank of a CRTC.
+type vblankWait struct {
+	fd       uintptr
+	crtcID   uint32
+	typ      uint32 // drm.EventVBlank or drm.EventCrtcSequence
+	userData uint64
+	target   uint64
+}
+
+// bits of the CRTC index in the type of a legacy vblank request
+const (
+	vblankSecondary     = 0x20000000
+	vblankHighCrtcMask  = 0x0000003e
+	vblankHighCrtcShift = 1
+)
+
+const waitVBlankFlags = drm.VBlankRelative | drm.VBlankEvent |
+	drm.VBlankNextOnMiss | vblankSecondary | vblankHighCrtcMask
+
+// VBlank makes every active CRTC of the card reach its next vertical
+// blank: the sequence of each CRTC is incremented, the page flips and
+// the events waiting for it are done, and WaitVBlank requests blocked
+// on it return. The events are written to the devices that requested
+// them.
+func (c *Card) VBlank() {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Since(c.created).Truncate(time.Microsecond)
+	for _, crtc := range c.Crtcs {
+		if crtc.Active {
+			crtc.Sequence++
+			crtc.VBlankTime = now
+		}
+		f, ok := c.flips[crtc.ID]
+		if !ok || (crtc.Active && crtc.Sequence < f.target) {
+			continue
+		}
+		delete(c.flips, crtc.ID)
+		if f.fbID != 0 {
+			crtc.FbID = f.fbID
+			if primary := c.plane(crtc.Primary); primary != nil {
+				primary.FbID = f.fbID
+			}
+		}
+		if f.event {
+			c.sendEvent(f.fd, drm.EventFlipComplete, f.userData, crtc)
+		}
+	}
+
+	waits := c.waits[:0]
+	for _, wait := range c.waits {
+		crtc := c.crtc(wait.crtcID)
+		if crtc != nil && crtc.Sequence < wait.target {
+			waits = append(waits, wait)
+			continue
+		}
+		if crtc != nil {
+			c.sendEvent(wait.fd, wait.typ, wait.userData, crtc)
+		}
+	}
+	c.waits = waits
+	c.vblank.Broadcast()
+}
+
+// sendEvent writes an event of crtc to the device with the file
+// descriptor fd.
+func (c *Card) sendEvent(fd uintptr, typ uint32, userData uint64, crtc *Crtc) {
+	pipe, ok := c.pipes[fd]
+	if !ok {
+		return
+	}
+	if typ == drm.EventCrtcSequence {
+		ev := sysEventCrtcSequence{
+			typ:      typ,
+			length:   uint32(unsafe.Sizeof(sysEventCrtcSequence{})),
+			userData: userData,
+			timeNs:   int64(crtc.VBlankTime),
+			sequence: crtc.Sequence,
+		}
+		pipe.Write(unsafe.Slice((*byte)(unsafe.Pointer(&ev)), ev.length))
+		return
+	}
+	ev := sysEventVBlank{
+		typ:      typ,
+		length:   uint32(unsafe.Sizeof(sysEventVBlank{})),
+		userData: userData,
+		tvSec:    uint32(crtc.VBlankTime / time.Second),
+		tvUsec:   uint32(crtc.VBlankTime % time.Second / time.Microsecond),
+		sequence: uint32(crtc.Sequence),
+		crtcID:   crtc.ID,
+	}
+	pipe.Write(unsafe.Slice((*byte)(unsafe.Pointer(&ev)), ev.length))
+}
+
+// queueWait queues an event for the vblank target of crtc, or sends it
+// at once if the vblank is past.
+func (c *Card) queueWait(fd uintptr, crtc *Crtc, typ uint32, userData, target uint64) {
+	if target <= crtc.Sequence {
+		c.sendEvent(fd, typ, userData, crtc)
+		return
+	}
+	c.waits = append(c.waits, &vblankWait{
+		fd:       fd,
+		crtcID:   crtc.ID,
+		typ:      typ,
+		userData: userData,
+		target:   target,
+	})
+}
+
+func (c *Card) waitVBlank(fd uintptr, req *sysWaitVBlank) error {
+	if req.typ&^waitVBlankFlags != 0 {
+		return syscall.EINVAL
+	}
+	index := 0
+	if high := req.typ & vblankHighCrtcMask; high != 0 {
+		if c.Caps[drm.CapVBlankHighCRTC] == 0 {
+			return syscall.EINVAL
+		}
+		index = int(high >> vblankHighCrtcShift)
+	} else if req.typ&vblankSecondary != 0 {
+		index = 1
+	}
+	if index >= len(c.Crtcs) || !c.Crtcs[index].Active {
+		return syscall.EINVAL
+	}
+	crtc := c.Crtcs[index]
+
+	// widen the 32 bits sequence around the current one
+	target := crtc.Sequence + uint64(int64(int32(req.sequence-uint32(crtc.Sequence))))
+	if req.typ&drm.VBlankRelative != 0 {
+		// like the kernel, make the request absolute so it can be
+		// restarted after an interruption
+		target = crtc.Sequence + uint64(req.sequence)
+		req.typ &^= drm.VBlankRelative
+	}
+	if req.typ&drm.VBlankNextOnMiss != 0 && target <= crtc.Sequence {
+		target = crtc.Sequence + 1
+	}
+	req.sequence = uint32(target)
+
+	if req.typ&drm.VBlankEvent != 0 {
+		c.queueWait(fd, crtc, drm.EventVBlank, uint64(req.tvalSec), target)
+		return nil
+	}
+	for crtc.Active && crtc.Sequence < target {
+		c.vblank.Wait()
+	}
+	if !crtc.Active {
+		return syscall.EINVAL
+	}
+	req.sequence = uint32(crtc.Sequence)
+	req.tvalSec = int(crtc.VBlankTime / time.Second)
+	req.tvalUsec = int(crtc.VBlankTime % time.Second / time.Microsecond)
+	return nil
+}
+
+func (c *Card) getCrtcSequence(req *sysCrtcGetSequence) error {
+	crtc := c.crtc(req.crtcID)
+	if crtc == nil {
+		return syscall.ENOENT
+	}
+	req.active = 0
+	if crtc.Active {
+		req.active = 1
+	}
+	req.sequence = crtc.Sequence
+	req.sequenceNs = int64(crtc.VBlankTime)
+	return nil
+}
+
+func (c *Card) queueCrtcSequence(fd uintptr, req *sysCrtcQueueSequence) error {
+	if req.flags&^(drm.CrtcSequenceRelative|drm.CrtcSequenceNextOnMiss) != 0 {
+		return syscall.EINVAL
+	}
+	crtc := c.crtc(req.crtcID)
+	if crtc == nil {
+		return syscall.ENOENT
+	}
+	if !crtc.Active {
+		return syscall.EINVAL
+	}
+	target := req.sequence
+	if req.flags&drm.CrtcSequenceRelative != 0 {
+		target += crtc.Sequence
+	}
+	if req.flags&drm.CrtcSequenceNextOnMiss != 0 && target <= crtc.Sequence {
+		target = crtc.Sequence + 1
+	}
+	req.sequence = target
+	c.queueWait(fd, crtc, drm.EventCrtcSequence, req.userData, target)
+	return nil
+}
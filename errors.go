@@ -0,0 +1,118 @@
+package drm
+
+import (
+	"errors"
+	"syscall"
+
+	"github.com/NeowayLabs/drm/ioctl"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// Errors reported by the failed requests, inside an *ioctl.Error.
+// Use errors.Is to test for them:
+//
+//	err := dev.SetCrtc(...)
+//	if errors.Is(err, drm.ErrNotMaster) {
+//		// wait to become master again
+//	}
+var (
+	ErrNotMaster     = errors.New("drm: not the DRM master")
+	ErrNoDumbBuffers = errors.New("drm: dumb buffers not supported")
+	ErrUnsupported   = errors.New("drm: not supported by the driver")
+	ErrBusy          = errors.New("drm: device busy")
+	ErrNotFound      = errors.New("drm: object not found")
+	ErrInvalidMode   = errors.New("drm: invalid mode configuration")
+	ErrBadMagic      = errors.New("drm: unknown magic")
+)
+
+// ErrNoClientCap is returned by the Device methods needing a client
+// capability not enabled with Device.SetClientCap.
+var ErrNoClientCap = errors.New("drm: client capability not enabled")
+
+var (
+	// errors of any mode-setting request
+	modeErrors = map[syscall.Errno]error{
+		syscall.EOPNOTSUPP: ErrUnsupported,
+		syscall.ENOENT:     ErrNotFound,
+	}
+
+	// errors of the requests changing the display configuration, that
+	// only the DRM master can do.
+	modesetErrors = map[syscall.Errno]error{
+		syscall.EACCES: ErrNotMaster,
+		syscall.EPERM:  ErrNotMaster,
+		syscall.EBUSY:  ErrBusy,
+		syscall.EINVAL: ErrInvalidMode,
+		syscall.ERANGE: ErrInvalidMode,
+		syscall.ENOSPC: ErrInvalidMode,
+	}
+
+	dumbErrors = map[syscall.Errno]error{
+		syscall.ENOSYS:     ErrNoDumbBuffers,
+		syscall.EOPNOTSUPP: ErrNoDumbBuffers,
+	}
+)
+
+func init() {
+	ioctl.RegisterErrors(IOCTLGetCap, map[syscall.Errno]error{
+		syscall.EINVAL: ErrUnsupported,
+	})
+	ioctl.RegisterErrors(IOCTLSetClientCap, map[syscall.Errno]error{
+		syscall.EINVAL:     ErrUnsupported,
+		syscall.EOPNOTSUPP: ErrUnsupported,
+	})
+	ioctl.RegisterErrors(IOCTLSetMaster, map[syscall.Errno]error{
+		syscall.EBUSY:  ErrBusy,
+		syscall.EACCES: ErrNotMaster,
+		syscall.EPERM:  ErrNotMaster,
+	})
+	ioctl.RegisterErrors(IOCTLDropMaster, map[syscall.Errno]error{
+		syscall.EINVAL: ErrNotMaster,
+		syscall.EACCES: ErrNotMaster,
+	})
+	ioctl.RegisterErrors(IOCTLAuthMagic, map[syscall.Errno]error{
+		syscall.EACCES: ErrNotMaster,
+		syscall.EINVAL: ErrBadMagic,
+	})
+
+	for _, code := range []uint32{
+		IOCTLWaitVBlank,
+		IOCTLCrtcGetSequence,
+		IOCTLCrtcQueueSequence,
+		mode.IOCTLModeResources,
+		mode.IOCTLModeGetConnector,
+		mode.IOCTLModeGetEncoder,
+		mode.IOCTLModeGetCrtc,
+		mode.IOCTLModeSetCrtc,
+		mode.IOCTLModeAddFB,
+		mode.IOCTLModeAddFB2,
+		mode.IOCTLModeRmFB,
+		mode.IOCTLModeGetPlaneResources,
+		mode.IOCTLModeGetPlane,
+		mode.IOCTLModeSetPlane,
+		mode.IOCTLModeGetProperty,
+		mode.IOCTLModeObjGetProperties,
+		mode.IOCTLModeObjSetProperty,
+		mode.IOCTLModeGetPropBlob,
+		mode.IOCTLModeCreatePropBlob,
+		mode.IOCTLModeDestroyPropBlob,
+		mode.IOCTLModeAtomic,
+		mode.IOCTLModePageFlip,
+	} {
+		ioctl.RegisterErrors(code, modeErrors)
+	}
+	ioctl.RegisterErrors(mode.IOCTLModeSetCrtc, modesetErrors)
+	ioctl.RegisterErrors(mode.IOCTLModeSetPlane, modesetErrors)
+	ioctl.RegisterErrors(mode.IOCTLModeObjSetProperty, modesetErrors)
+	ioctl.RegisterErrors(mode.IOCTLModeAtomic, modesetErrors)
+	ioctl.RegisterErrors(mode.IOCTLModePageFlip, modesetErrors)
+
+	for _, code := range []uint32{
+		mode.IOCTLModeCreateDumb,
+		mode.IOCTLModeMapDumb,
+		mode.IOCTLModeDestroyDumb,
+	} {
+		ioctl.RegisterErrors(code, modeErrors)
+		ioctl.RegisterErrors(code, dumbErrors)
+	}
+}
@@ -0,0 +1,85 @@
+package drm_test
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/ioctl"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestErrors(t *testing.T) {
+	card := drmtest.New()
+	conn := card.AddHead(drmtest.Mode(640, 480, 60))
+	crtcID := card.Crtcs[0].ID
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	fb, err := dev.CreateFB(640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := dev.AddFB(640, 480, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCrtc := func() error {
+		return dev.SetCrtc(crtcID, fbID, 0, 0, &conn.ID, 1, &conn.Modes[0])
+	}
+
+	for _, test := range []struct {
+		code     uint32
+		errno    syscall.Errno
+		call     func() error
+		expected error
+	}{
+		{mode.IOCTLModeSetCrtc, syscall.EACCES, setCrtc, drm.ErrNotMaster},
+		{mode.IOCTLModeSetCrtc, syscall.EBUSY, setCrtc, drm.ErrBusy},
+		{mode.IOCTLModeSetCrtc, syscall.EINVAL, setCrtc, drm.ErrInvalidMode},
+		{mode.IOCTLModeGetCrtc, syscall.ENOENT, func() error {
+			_, err := dev.GetCrtc(crtcID)
+			return err
+		}, drm.ErrNotFound},
+		{mode.IOCTLModeCreateDumb, syscall.ENOSYS, func() error {
+			_, err := dev.CreateFB(640, 480, 32)
+			return err
+		}, drm.ErrNoDumbBuffers},
+		{drm.IOCTLGetCap, syscall.EINVAL, func() error {
+			_, err := drm.GetCap(dev, drm.CapPrime)
+			return err
+		}, drm.ErrUnsupported},
+	} {
+		card.Inject(test.code, test.errno)
+		err := test.call()
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v but got %v", test.expected, err)
+		}
+		if !errors.Is(err, test.errno) {
+			t.Errorf("Expected errno %v in %v", test.errno, err)
+		}
+		var ioerr *ioctl.Error
+		if !errors.As(err, &ioerr) {
+			t.Errorf("Expected *ioctl.Error but got %T", err)
+			continue
+		}
+		if ioerr.Name != ioctl.Name(test.code) ||
+			ioerr.Code.Uint32() != test.code {
+			t.Errorf("Unexpected request in %#v", ioerr)
+		}
+	}
+
+	card.Inject(mode.IOCTLModeSetCrtc, syscall.EACCES)
+	err = setCrtc()
+	expected := "ioctl DRM_IOCTL_MODE_SETCRTC: drm: not the DRM master (permission denied)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q but got %q", expected, err)
+	}
+	if errors.Is(err, drm.ErrBusy) {
+		t.Errorf("%v should not be %v", err, drm.ErrBusy)
+	}
+}
@@ -0,0 +1,172 @@
+package drm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// AnyCrtc registers an EventLoop handler or channel for the events of
+// every CRTC, including the events without a CRTC id.
+const AnyCrtc = 0
+
+// eventsBuffer is the capacity of the channels of EventLoop.Events.
+const eventsBuffer = 16
+
+// EventLoop reads the events of a device and dispatches them to the
+// handlers and channels registered for their CRTC. The device is read
+// through the runtime netpoller, so a waiting loop holds no thread and
+// stops as soon as its context is done or the device is closed:
+//
+//	loop := drm.NewEventLoop(dev)
+//	flips := loop.Events(crtcID)
+//	go loop.Run(ctx)
+//
+//	dev.PageFlip(crtcID, fbID, mode.PageFlipEvent, 0)
+//	ev := <-flips
+type EventLoop struct {
+	dev *Device
+
+	mu       sync.Mutex
+	handlers map[uint32][]func(Event)
+	chans    map[uint32][]chan Event
+	started  bool
+	stopped  bool
+}
+
+var errLoopStarted = errors.New("drm: event loop already started")
+
+// NewEventLoop returns an event loop reading the events of dev. No
+// other reader of the device should run along with the loop.
+func NewEventLoop(dev *Device) *EventLoop {
+	return &EventLoop{
+		dev:      dev,
+		handlers: make(map[uint32][]func(Event)),
+		chans:    make(map[uint32][]chan Event),
+	}
+}
+
+// Handle makes the loop call fn with each event of the CRTC crtcID, or
+// with every event for AnyCrtc. fn is called from the goroutine running
+// the loop, and delays the next events until it returns.
+func (l *EventLoop) Handle(crtcID uint32, fn func(Event)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[crtcID] = append(l.handlers[crtcID], fn)
+}
+
+// Events returns a channel receiving the events of the CRTC crtcID, or
+// every event for AnyCrtc. Once the channel buffer is full, the loop
+// waits for the events to be received, so the channel must be drained.
+// It is closed when the loop stops.
+func (l *EventLoop) Events(crtcID uint32) <-chan Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch := make(chan Event, eventsBuffer)
+	if l.stopped {
+		close(ch)
+		return ch
+	}
+	l.chans[crtcID] = append(l.chans[crtcID], ch)
+	return ch
+}
+
+// Run reads and dispatches the events of the device until ctx is done,
+// returning ctx.Err(), or until the device is closed, returning nil.
+// Other read errors stop the loop too. The events already read when ctx
+// is done are still dispatched, but not to the channels that are full.
+// A loop runs only once.
+func (l *EventLoop) Run(ctx context.Context) error {
+	l.mu.Lock()
+	if l.started {
+		l.mu.Unlock()
+		return errLoopStarted
+	}
+	l.started = true
+	l.mu.Unlock()
+	defer l.stop()
+
+	// interrupt the blocked read when ctx is done
+	file := l.dev.file
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		file.SetReadDeadline(time.Now())
+		close(interrupted)
+	})
+	defer func() {
+		if !stop() {
+			<-interrupted
+			file.SetReadDeadline(time.Time{})
+		}
+	}()
+
+	buf := make([]byte, eventBufferSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			events, err := ParseEvents(buf[:n])
+			for _, ev := range events {
+				if err := l.dispatch(ctx, ev); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, os.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (l *EventLoop) dispatch(ctx context.Context, ev Event) error {
+	l.mu.Lock()
+	handlers := l.handlers[AnyCrtc]
+	chans := l.chans[AnyCrtc]
+	if ev.CrtcID != AnyCrtc {
+		handlers = append(handlers[:len(handlers):len(handlers)],
+			l.handlers[ev.CrtcID]...)
+		chans = append(chans[:len(chans):len(chans)], l.chans[ev.CrtcID]...)
+	}
+	l.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(ev)
+	}
+	for _, ch := range chans {
+		// fill the buffer first, even once ctx is done
+		select {
+		case ch <- ev:
+			continue
+		default:
+		}
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// stop closes the channels of the loop.
+func (l *EventLoop) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = true
+	for _, chans := range l.chans {
+		for _, ch := range chans {
+			close(ch)
+		}
+	}
+	l.chans = nil
+}
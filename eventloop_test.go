@@ -0,0 +1,177 @@
+package drm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// flipCard returns a card with two lit heads, opened by dev, and a
+// framebuffer to flip to.
+func flipCard(t *testing.T) (*drmtest.Card, *drm.Device, uint32) {
+	card := drmtest.New()
+	for i := 0; i < 2; i++ {
+		card.AddHead(drmtest.Mode(640, 480, 60))
+	}
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dev.Close() })
+	fb, err := dev.CreateFB(640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := dev.AddFB(640, 480, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, conn := range card.Connectors {
+		info := conn.Modes[0]
+		err := dev.SetCrtc(card.Crtcs[i].ID, fbID, 0, 0, &conn.ID, 1, &info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return card, dev, fbID
+}
+
+func receive(t *testing.T, events <-chan drm.Event) drm.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event received")
+	}
+	return drm.Event{}
+}
+
+func TestEventLoop(t *testing.T) {
+	card, dev, fbID := flipCard(t)
+	crtc1, crtc2 := card.Crtcs[0], card.Crtcs[1]
+
+	loop := drm.NewEventLoop(dev)
+	flips1, flips2 := loop.Events(crtc1.ID), loop.Events(crtc2.ID)
+	var (
+		mu  sync.Mutex
+		all []drm.Event
+	)
+	loop.Handle(drm.AnyCrtc, func(ev drm.Event) {
+		mu.Lock()
+		all = append(all, ev)
+		mu.Unlock()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- loop.Run(ctx) }()
+
+	if err := dev.PageFlip(crtc2.ID, fbID, mode.PageFlipEvent, 2); err != nil {
+		t.Fatal(err)
+	}
+	card.VBlank()
+	if ev := receive(t, flips2); ev.CrtcID != crtc2.ID || ev.UserData != 2 {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if err := dev.PageFlip(crtc1.ID, fbID, mode.PageFlipEvent, 1); err != nil {
+		t.Fatal(err)
+	}
+	card.VBlank()
+	if ev := receive(t, flips1); ev.CrtcID != crtc1.ID || ev.Sequence != 2 {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected %v but got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Loop not stopped by the context")
+	}
+	if _, ok := <-flips1; ok {
+		t.Errorf("Channel not closed")
+	}
+	if _, ok := <-loop.Events(crtc1.ID); ok {
+		t.Errorf("Channel of a stopped loop not closed")
+	}
+	mu.Lock()
+	if len(all) != 2 || all[0].UserData != 2 || all[1].UserData != 1 {
+		t.Errorf("Unexpected events: %+v", all)
+	}
+	mu.Unlock()
+	if err := loop.Run(context.Background()); err == nil {
+		t.Errorf("Expected error running the loop again")
+	}
+
+	// the device can still be read once the loop is done
+	if err := dev.PageFlip(crtc1.ID, fbID, mode.PageFlipEvent, 3); err != nil {
+		t.Fatal(err)
+	}
+	card.VBlank()
+	events, err := dev.ReadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].UserData != 3 {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+}
+
+func TestEventLoopCancelled(t *testing.T) {
+	card, dev, fbID := flipCard(t)
+	for i, crtc := range card.Crtcs {
+		err := dev.PageFlip(crtc.ID, fbID, mode.PageFlipEvent, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	card.VBlank() // both events are read at once
+
+	loop := drm.NewEventLoop(dev)
+	events := loop.Events(drm.AnyCrtc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	loop.Handle(drm.AnyCrtc, func(drm.Event) { cancel() })
+	if err := loop.Run(ctx); err != context.Canceled {
+		t.Errorf("Expected %v but got %v", context.Canceled, err)
+	}
+	var got []uint64
+	for ev := range events {
+		got = append(got, ev.UserData)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("Expected the events read before the cancel but got %v", got)
+	}
+}
+
+func TestEventLoopDeviceClosed(t *testing.T) {
+	_, dev, _ := flipCard(t)
+	loop := drm.NewEventLoop(dev)
+	events := loop.Events(drm.AnyCrtc)
+	done := make(chan error)
+	go func() { done <- loop.Run(context.Background()) }()
+
+	time.Sleep(10 * time.Millisecond) // let the loop block on the read
+	if err := dev.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Loop not stopped by closing the device")
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("Channel not closed")
+	}
+}
@@ -0,0 +1,123 @@
+package drm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unsafe"
+)
+
+// Event types
+const (
+	EventVBlank       = 0x01
+	EventFlipComplete = 0x02
+	EventCrtcSequence = 0x03
+)
+
+// The events are decoded field by field, at the offsets of these
+// structures: the events in a read buffer are not aligned.
+type (
+	sysEvent struct {
+		typ    uint32
+		length uint32
+	}
+
+	sysEventVBlank struct {
+		sysEvent
+		userData uint64
+		tvSec    uint32
+		tvUsec   uint32
+		sequence uint32
+		crtcID   uint32
+	}
+
+	sysEventCrtcSequence struct {
+		sysEvent
+		userData uint64
+		timeNs   int64
+		sequence uint64
+	}
+
+	// Event is a vblank, flip complete or CRTC sequence event read from
+	// the device.
+	Event struct {
+		Type     uint32
+		UserData uint64 // given when the event was requested
+		Sequence uint64 // vblank counter of the CRTC
+
+		// Time of the vblank, CLOCK_MONOTONIC if the driver has
+		// CapTimestampMonotonic.
+		Time time.Duration
+
+		// CrtcID is zero for CRTC sequence events, and for vblank
+		// events of kernels older than 4.12.
+		CrtcID uint32
+	}
+)
+
+// eventBufferSize fits many events, a read never returns less than a
+// whole event.
+const eventBufferSize = 4096
+
+// ReadEvents reads the pending events of the device, blocking until
+// there is at least one.
+func ReadEvents(file io.Reader) ([]Event, error) {
+	buf := make([]byte, eventBufferSize)
+	n, err := file.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEvents(buf[:n])
+}
+
+// ParseEvents decodes the events in buf, as read from the device.
+// Events of unknown types (eg.: driver specific ones) are skipped.
+func ParseEvents(buf []byte) ([]Event, error) {
+	var events []Event
+	for len(buf) > 0 {
+		if len(buf) < int(unsafe.Sizeof(sysEvent{})) {
+			return events, errShortEvent
+		}
+		typ := binary.NativeEndian.Uint32(buf[0:])
+		length := binary.NativeEndian.Uint32(buf[4:])
+		if length < uint32(unsafe.Sizeof(sysEvent{})) ||
+			uint64(length) > uint64(len(buf)) {
+			return events, errShortEvent
+		}
+
+		switch typ {
+		case EventVBlank, EventFlipComplete:
+			if length < uint32(unsafe.Sizeof(sysEventVBlank{})) {
+				return events, fmt.Errorf("drm: event %d too short: %d bytes",
+					typ, length)
+			}
+			tvSec := binary.NativeEndian.Uint32(buf[16:])
+			tvUsec := binary.NativeEndian.Uint32(buf[20:])
+			events = append(events, Event{
+				Type:     typ,
+				UserData: binary.NativeEndian.Uint64(buf[8:]),
+				Sequence: uint64(binary.NativeEndian.Uint32(buf[24:])),
+				Time: time.Duration(tvSec)*time.Second +
+					time.Duration(tvUsec)*time.Microsecond,
+				CrtcID: binary.NativeEndian.Uint32(buf[28:]),
+			})
+		case EventCrtcSequence:
+			if length < uint32(unsafe.Sizeof(sysEventCrtcSequence{})) {
+				return events, fmt.Errorf("drm: event %d too short: %d bytes",
+					typ, length)
+			}
+			events = append(events, Event{
+				Type:     typ,
+				UserData: binary.NativeEndian.Uint64(buf[8:]),
+				Sequence: binary.NativeEndian.Uint64(buf[24:]),
+				Time:     time.Duration(int64(binary.NativeEndian.Uint64(buf[16:]))),
+			})
+		}
+		buf = buf[length:]
+	}
+	return events, nil
+}
+
+var errShortEvent = errors.New("drm: truncated event")
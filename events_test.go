@@ -0,0 +1,79 @@
+package drm_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/drm"
+)
+
+// event encodes an event the way the kernel does.
+func event(typ uint32, userData uint64, fields ...uint64) []byte {
+	buf := binary.NativeEndian.AppendUint32(nil, typ)
+	buf = binary.NativeEndian.AppendUint32(buf, 0)
+	buf = binary.NativeEndian.AppendUint64(buf, userData)
+	for _, field := range fields {
+		if typ == drm.EventCrtcSequence {
+			buf = binary.NativeEndian.AppendUint64(buf, field)
+		} else {
+			buf = binary.NativeEndian.AppendUint32(buf, uint32(field))
+		}
+	}
+	binary.NativeEndian.PutUint32(buf[4:], uint32(len(buf)))
+	return buf
+}
+
+func TestParseEvents(t *testing.T) {
+	var buf []byte
+	buf = append(buf, event(drm.EventVBlank, 1, 10, 500, 600, 0)...)
+	buf = append(buf, event(0x80000000, 0, 1, 2)...) // driver specific
+	buf = append(buf, event(drm.EventFlipComplete, 2, 11, 0, 601, 42)...)
+	buf = append(buf, event(drm.EventCrtcSequence, 3, uint64(12*time.Second), 1<<40)...)
+
+	events, err := drm.ParseEvents(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []drm.Event{
+		{
+			Type:     drm.EventVBlank,
+			UserData: 1,
+			Sequence: 600,
+			Time:     10*time.Second + 500*time.Microsecond,
+		},
+		{
+			Type:     drm.EventFlipComplete,
+			UserData: 2,
+			Sequence: 601,
+			Time:     11 * time.Second,
+			CrtcID:   42,
+		},
+		{
+			Type:     drm.EventCrtcSequence,
+			UserData: 3,
+			Sequence: 1 << 40,
+			Time:     12 * time.Second,
+		},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+	// the events of a buffer are not aligned
+	unaligned := append([]byte{0}, buf...)[1:]
+	if events, err := drm.ParseEvents(unaligned); err != nil ||
+		!reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected unaligned events: %+v, %v", events, err)
+	}
+
+	for _, bad := range [][]byte{
+		buf[:4],
+		buf[:len(buf)-1],
+		event(drm.EventVBlank, 0, 1, 2),
+	} {
+		if _, err := drm.ParseEvents(bad); err == nil {
+			t.Errorf("Expected error for %d bytes", len(bad))
+		}
+	}
+}
@@ -2,10 +2,56 @@ package drm_test
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
 
 	"github.com/NeowayLabs/drm"
 )
 
+// The examples need a known graphics card, but go test runs the
+// examples with an Output comment unconditionally: TestExamples runs
+// them and checks their output instead, when there is a card.
+func TestExamples(t *testing.T) {
+	needCard(t)
+	for _, test := range []struct {
+		name   string
+		fn     func()
+		output string
+	}{
+		{"HasDumbBuffer", ExampleHasDumbBuffer, "ok"},
+		{"ListDevices", ExampleListDevices, "Driver name: " + card.Name + "\n"},
+	} {
+		output := captureStdout(t, test.fn)
+		if !strings.Contains(output, test.output) {
+			t.Errorf("Example%s: expected output %q but got %q", test.name,
+				test.output, output)
+		}
+	}
+}
+
+// captureStdout returns what fn writes to the standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	return <-output
+}
+
 func ExampleHasDumbBuffer() {
 	// This example shows how to test if your graphics card
 	// supports 'dumb buffers' capability. With this capability
@@ -24,7 +70,7 @@ func ExampleHasDumbBuffer() {
 	}
 	fmt.Printf("ok")
 
-	// Output: ok
+	// Output (with an i915 card): ok
 }
 
 func ExampleListDevices() {
@@ -33,5 +79,5 @@ func ExampleListDevices() {
 		fmt.Printf("Driver name: %s\n", dev.Name)
 	}
 
-	// Output: Driver name: i915
+	// Output (with an i915 card): Driver name: i915
 }
@@ -0,0 +1,218 @@
+// Package fourcc holds the pixel formats of the DRM framebuffers and
+// planes, the DRM_FORMAT_* codes of drm_fourcc.h, with the layout of
+// their planes.
+//
+// A format code is four characters in a little-endian uint32, like
+// "XR24" for XRGB8888: 32 bits per pixel holding, from the most
+// significant bits, an unused byte and the red, green and blue bytes.
+package fourcc
+
+import "fmt"
+
+// Format is a fourcc pixel format code.
+type Format uint32
+
+// Invalid is the format code of none.
+const Invalid Format = 0
+
+// BigEndian is set on the codes of formats in big-endian byte order,
+// instead of the default little-endian.
+const BigEndian Format = 1 << 31
+
+const (
+	// color index
+	C1 Format = 'C' | '1'<<8 | ' '<<16 | ' '<<24
+	C2 Format = 'C' | '2'<<8 | ' '<<16 | ' '<<24
+	C4 Format = 'C' | '4'<<8 | ' '<<16 | ' '<<24
+	C8 Format = 'C' | '8'<<8 | ' '<<16 | ' '<<24
+
+	// darkness, inverted single channel
+	D1 Format = 'D' | '1'<<8 | ' '<<16 | ' '<<24
+	D2 Format = 'D' | '2'<<8 | ' '<<16 | ' '<<24
+	D4 Format = 'D' | '4'<<8 | ' '<<16 | ' '<<24
+	D8 Format = 'D' | '8'<<8 | ' '<<16 | ' '<<24
+
+	// single channel
+	R1  Format = 'R' | '1'<<8 | ' '<<16 | ' '<<24
+	R2  Format = 'R' | '2'<<8 | ' '<<16 | ' '<<24
+	R4  Format = 'R' | '4'<<8 | ' '<<16 | ' '<<24
+	R8  Format = 'R' | '8'<<8 | ' '<<16 | ' '<<24
+	R10 Format = 'R' | '1'<<8 | '0'<<16 | ' '<<24
+	R12 Format = 'R' | '1'<<8 | '2'<<16 | ' '<<24
+	R16 Format = 'R' | '1'<<8 | '6'<<16 | ' '<<24
+
+	// two channels
+	RG88   Format = 'R' | 'G'<<8 | '8'<<16 | '8'<<24
+	GR88   Format = 'G' | 'R'<<8 | '8'<<16 | '8'<<24
+	RG1616 Format = 'R' | 'G'<<8 | '3'<<16 | '2'<<24
+	GR1616 Format = 'G' | 'R'<<8 | '3'<<16 | '2'<<24
+
+	// 8 bpp RGB
+	RGB332 Format = 'R' | 'G'<<8 | 'B'<<16 | '8'<<24
+	BGR233 Format = 'B' | 'G'<<8 | 'R'<<16 | '8'<<24
+
+	// 16 bpp RGB
+	XRGB4444 Format = 'X' | 'R'<<8 | '1'<<16 | '2'<<24
+	XBGR4444 Format = 'X' | 'B'<<8 | '1'<<16 | '2'<<24
+	RGBX4444 Format = 'R' | 'X'<<8 | '1'<<16 | '2'<<24
+	BGRX4444 Format = 'B' | 'X'<<8 | '1'<<16 | '2'<<24
+	ARGB4444 Format = 'A' | 'R'<<8 | '1'<<16 | '2'<<24
+	ABGR4444 Format = 'A' | 'B'<<8 | '1'<<16 | '2'<<24
+	RGBA4444 Format = 'R' | 'A'<<8 | '1'<<16 | '2'<<24
+	BGRA4444 Format = 'B' | 'A'<<8 | '1'<<16 | '2'<<24
+	XRGB1555 Format = 'X' | 'R'<<8 | '1'<<16 | '5'<<24
+	XBGR1555 Format = 'X' | 'B'<<8 | '1'<<16 | '5'<<24
+	RGBX5551 Format = 'R' | 'X'<<8 | '1'<<16 | '5'<<24
+	BGRX5551 Format = 'B' | 'X'<<8 | '1'<<16 | '5'<<24
+	ARGB1555 Format = 'A' | 'R'<<8 | '1'<<16 | '5'<<24
+	ABGR1555 Format = 'A' | 'B'<<8 | '1'<<16 | '5'<<24
+	RGBA5551 Format = 'R' | 'A'<<8 | '1'<<16 | '5'<<24
+	BGRA5551 Format = 'B' | 'A'<<8 | '1'<<16 | '5'<<24
+	RGB565   Format = 'R' | 'G'<<8 | '1'<<16 | '6'<<24
+	BGR565   Format = 'B' | 'G'<<8 | '1'<<16 | '6'<<24
+
+	// 24 bpp RGB
+	RGB888 Format = 'R' | 'G'<<8 | '2'<<16 | '4'<<24
+	BGR888 Format = 'B' | 'G'<<8 | '2'<<16 | '4'<<24
+
+	// 32 bpp RGB
+	XRGB8888    Format = 'X' | 'R'<<8 | '2'<<16 | '4'<<24
+	XBGR8888    Format = 'X' | 'B'<<8 | '2'<<16 | '4'<<24
+	RGBX8888    Format = 'R' | 'X'<<8 | '2'<<16 | '4'<<24
+	BGRX8888    Format = 'B' | 'X'<<8 | '2'<<16 | '4'<<24
+	ARGB8888    Format = 'A' | 'R'<<8 | '2'<<16 | '4'<<24
+	ABGR8888    Format = 'A' | 'B'<<8 | '2'<<16 | '4'<<24
+	RGBA8888    Format = 'R' | 'A'<<8 | '2'<<16 | '4'<<24
+	BGRA8888    Format = 'B' | 'A'<<8 | '2'<<16 | '4'<<24
+	XRGB2101010 Format = 'X' | 'R'<<8 | '3'<<16 | '0'<<24
+	XBGR2101010 Format = 'X' | 'B'<<8 | '3'<<16 | '0'<<24
+	RGBX1010102 Format = 'R' | 'X'<<8 | '3'<<16 | '0'<<24
+	BGRX1010102 Format = 'B' | 'X'<<8 | '3'<<16 | '0'<<24
+	ARGB2101010 Format = 'A' | 'R'<<8 | '3'<<16 | '0'<<24
+	ABGR2101010 Format = 'A' | 'B'<<8 | '3'<<16 | '0'<<24
+	RGBA1010102 Format = 'R' | 'A'<<8 | '3'<<16 | '0'<<24
+	BGRA1010102 Format = 'B' | 'A'<<8 | '3'<<16 | '0'<<24
+
+	// 64 bpp RGB
+	XRGB16161616         Format = 'X' | 'R'<<8 | '4'<<16 | '8'<<24
+	XBGR16161616         Format = 'X' | 'B'<<8 | '4'<<16 | '8'<<24
+	ARGB16161616         Format = 'A' | 'R'<<8 | '4'<<16 | '8'<<24
+	ABGR16161616         Format = 'A' | 'B'<<8 | '4'<<16 | '8'<<24
+	XRGB16161616F        Format = 'X' | 'R'<<8 | '4'<<16 | 'H'<<24
+	XBGR16161616F        Format = 'X' | 'B'<<8 | '4'<<16 | 'H'<<24
+	ARGB16161616F        Format = 'A' | 'R'<<8 | '4'<<16 | 'H'<<24
+	ABGR16161616F        Format = 'A' | 'B'<<8 | '4'<<16 | 'H'<<24
+	AXBXGXRX106106106106 Format = 'A' | 'B'<<8 | '1'<<16 | '0'<<24
+
+	// packed YCbCr
+	YUYV            Format = 'Y' | 'U'<<8 | 'Y'<<16 | 'V'<<24
+	YVYU            Format = 'Y' | 'V'<<8 | 'Y'<<16 | 'U'<<24
+	UYVY            Format = 'U' | 'Y'<<8 | 'V'<<16 | 'Y'<<24
+	VYUY            Format = 'V' | 'Y'<<8 | 'U'<<16 | 'Y'<<24
+	AYUV            Format = 'A' | 'Y'<<8 | 'U'<<16 | 'V'<<24
+	AVUY8888        Format = 'A' | 'V'<<8 | 'U'<<16 | 'Y'<<24
+	XYUV8888        Format = 'X' | 'Y'<<8 | 'U'<<16 | 'V'<<24
+	XVUY8888        Format = 'X' | 'V'<<8 | 'U'<<16 | 'Y'<<24
+	VUY888          Format = 'V' | 'U'<<8 | '2'<<16 | '4'<<24
+	VUY101010       Format = 'V' | 'U'<<8 | '3'<<16 | '0'<<24
+	Y210            Format = 'Y' | '2'<<8 | '1'<<16 | '0'<<24
+	Y212            Format = 'Y' | '2'<<8 | '1'<<16 | '2'<<24
+	Y216            Format = 'Y' | '2'<<8 | '1'<<16 | '6'<<24
+	Y410            Format = 'Y' | '4'<<8 | '1'<<16 | '0'<<24
+	Y412            Format = 'Y' | '4'<<8 | '1'<<16 | '2'<<24
+	Y416            Format = 'Y' | '4'<<8 | '1'<<16 | '6'<<24
+	XVYU2101010     Format = 'X' | 'V'<<8 | '3'<<16 | '0'<<24
+	XVYU12_16161616 Format = 'X' | 'V'<<8 | '3'<<16 | '6'<<24
+	XVYU16161616    Format = 'X' | 'V'<<8 | '4'<<16 | '8'<<24
+
+	// packed YCbCr in 2x2 blocks
+	Y0L0 Format = 'Y' | '0'<<8 | 'L'<<16 | '0'<<24
+	X0L0 Format = 'X' | '0'<<8 | 'L'<<16 | '0'<<24
+	Y0L2 Format = 'Y' | '0'<<8 | 'L'<<16 | '2'<<24
+	X0L2 Format = 'X' | '0'<<8 | 'L'<<16 | '2'<<24
+
+	// compressed YCbCr, only with modifiers
+	YUV420_8BIT  Format = 'Y' | 'U'<<8 | '0'<<16 | '8'<<24
+	YUV420_10BIT Format = 'Y' | 'U'<<8 | '1'<<16 | '0'<<24
+
+	// RGB with a separate alpha plane
+	XRGB8888_A8 Format = 'X' | 'R'<<8 | 'A'<<16 | '8'<<24
+	XBGR8888_A8 Format = 'X' | 'B'<<8 | 'A'<<16 | '8'<<24
+	RGBX8888_A8 Format = 'R' | 'X'<<8 | 'A'<<16 | '8'<<24
+	BGRX8888_A8 Format = 'B' | 'X'<<8 | 'A'<<16 | '8'<<24
+	RGB888_A8   Format = 'R' | '8'<<8 | 'A'<<16 | '8'<<24
+	BGR888_A8   Format = 'B' | '8'<<8 | 'A'<<16 | '8'<<24
+	RGB565_A8   Format = 'R' | '5'<<8 | 'A'<<16 | '8'<<24
+	BGR565_A8   Format = 'B' | '5'<<8 | 'A'<<16 | '8'<<24
+
+	// 2 planes YCbCr: luma, then interleaved chroma
+	NV12 Format = 'N' | 'V'<<8 | '1'<<16 | '2'<<24
+	NV21 Format = 'N' | 'V'<<8 | '2'<<16 | '1'<<24
+	NV16 Format = 'N' | 'V'<<8 | '1'<<16 | '6'<<24
+	NV61 Format = 'N' | 'V'<<8 | '6'<<16 | '1'<<24
+	NV24 Format = 'N' | 'V'<<8 | '2'<<16 | '4'<<24
+	NV42 Format = 'N' | 'V'<<8 | '4'<<16 | '2'<<24
+	NV15 Format = 'N' | 'V'<<8 | '1'<<16 | '5'<<24
+	NV20 Format = 'N' | 'V'<<8 | '2'<<16 | '0'<<24
+	NV30 Format = 'N' | 'V'<<8 | '3'<<16 | '0'<<24
+	P210 Format = 'P' | '2'<<8 | '1'<<16 | '0'<<24
+	P010 Format = 'P' | '0'<<8 | '1'<<16 | '0'<<24
+	P012 Format = 'P' | '0'<<8 | '1'<<16 | '2'<<24
+	P016 Format = 'P' | '0'<<8 | '1'<<16 | '6'<<24
+	P030 Format = 'P' | '0'<<8 | '3'<<16 | '0'<<24
+
+	// 3 planes YCbCr, 16 bits per component
+	Q410 Format = 'Q' | '4'<<8 | '1'<<16 | '0'<<24
+	Q401 Format = 'Q' | '4'<<8 | '0'<<16 | '1'<<24
+	S010 Format = 'S' | '0'<<8 | '1'<<16 | '0'<<24
+	S210 Format = 'S' | '2'<<8 | '1'<<16 | '0'<<24
+	S410 Format = 'S' | '4'<<8 | '1'<<16 | '0'<<24
+	S012 Format = 'S' | '0'<<8 | '1'<<16 | '2'<<24
+	S212 Format = 'S' | '2'<<8 | '1'<<16 | '2'<<24
+	S412 Format = 'S' | '4'<<8 | '1'<<16 | '2'<<24
+	S016 Format = 'S' | '0'<<8 | '1'<<16 | '6'<<24
+	S216 Format = 'S' | '2'<<8 | '1'<<16 | '6'<<24
+	S416 Format = 'S' | '4'<<8 | '1'<<16 | '6'<<24
+
+	// 3 planes YCbCr: luma, Cb, Cr
+	YUV410 Format = 'Y' | 'U'<<8 | 'V'<<16 | '9'<<24
+	YVU410 Format = 'Y' | 'V'<<8 | 'U'<<16 | '9'<<24
+	YUV411 Format = 'Y' | 'U'<<8 | '1'<<16 | '1'<<24
+	YVU411 Format = 'Y' | 'V'<<8 | '1'<<16 | '1'<<24
+	YUV420 Format = 'Y' | 'U'<<8 | '1'<<16 | '2'<<24
+	YVU420 Format = 'Y' | 'V'<<8 | '1'<<16 | '2'<<24
+	YUV422 Format = 'Y' | 'U'<<8 | '1'<<16 | '6'<<24
+	YVU422 Format = 'Y' | 'V'<<8 | '1'<<16 | '6'<<24
+	YUV444 Format = 'Y' | 'U'<<8 | '2'<<16 | '4'<<24
+	YVU444 Format = 'Y' | 'V'<<8 | '2'<<16 | '4'<<24
+)
+
+// Code returns the four characters of the format code, like "XR24".
+func (f Format) Code() string {
+	f &^= BigEndian
+	return string([]byte{byte(f), byte(f >> 8), byte(f >> 16), byte(f >> 24)})
+}
+
+// String returns the name of the format, like "XRGB8888", or its code
+// if the format is unknown.
+func (f Format) String() string {
+	suffix := ""
+	if f&BigEndian != 0 {
+		suffix = "_BE"
+	}
+	if info, ok := formats[f&^BigEndian]; ok {
+		return info.Name + suffix
+	}
+	for _, c := range f.Code() {
+		if c < ' ' || c > '~' {
+			return fmt.Sprintf("%#08x", uint32(f))
+		}
+	}
+	return f.Code() + suffix
+}
+
+// Info returns the layout of the format, if known.
+func (f Format) Info() (*FormatInfo, bool) {
+	info, ok := formats[f]
+	return info, ok
+}
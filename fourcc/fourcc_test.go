@@ -0,0 +1,103 @@
+package fourcc_test
+
+import (
+	"testing"
+
+	"github.com/NeowayLabs/drm/fourcc"
+)
+
+func TestFormatString(t *testing.T) {
+	for _, test := range []struct {
+		format     fourcc.Format
+		code, name string
+	}{
+		{fourcc.XRGB8888, "XR24", "XRGB8888"},
+		{fourcc.NV12, "NV12", "NV12"},
+		{fourcc.C8, "C8  ", "C8"},
+		{fourcc.XRGB16161616F, "XR4H", "XRGB16161616F"},
+		{fourcc.RGB565 | fourcc.BigEndian, "RG16", "RGB565_BE"},
+		{fourcc.Format('Z' | 'Z'<<8 | '9'<<16 | '9'<<24), "ZZ99", "ZZ99"},
+		{fourcc.Invalid, "\x00\x00\x00\x00", "0x00000000"},
+	} {
+		if code := test.format.Code(); code != test.code {
+			t.Errorf("Expected code %q but got %q", test.code, code)
+		}
+		if name := test.format.String(); name != test.name {
+			t.Errorf("Expected name %q but got %q", test.name, name)
+		}
+	}
+	if fourcc.XRGB8888 != 0x34325258 || fourcc.NV12 != 0x3231564e {
+		t.Errorf("Unexpected codes %#x %#x", fourcc.XRGB8888, fourcc.NV12)
+	}
+}
+
+func TestFormatInfo(t *testing.T) {
+	info, ok := fourcc.NV12.Info()
+	if !ok {
+		t.Fatal("No info of NV12")
+	}
+	if info.Planes != 2 || !info.IsYUV || info.HasAlpha ||
+		info.HSub != 2 || info.VSub != 2 {
+		t.Errorf("Unexpected NV12 info: %+v", info)
+	}
+	if w, h := info.PlaneWidth(1, 1921), info.PlaneHeight(1, 1081); w != 961 || h != 541 {
+		t.Errorf("Unexpected chroma plane %dx%d", w, h)
+	}
+	if pitch := info.MinPitch(1, 1920); pitch != 1920 {
+		t.Errorf("Unexpected chroma pitch %d", pitch)
+	}
+
+	info, _ = fourcc.ARGB8888.Info()
+	if info.BPP(0) != 32 || info.Depth != 32 || !info.HasAlpha ||
+		info.MinPitch(0, 100) != 400 {
+		t.Errorf("Unexpected ARGB8888 info: %+v", info)
+	}
+	info, _ = fourcc.C1.Info()
+	if info.BPP(0) != 1 || !info.ColorIndexed || info.MinPitch(0, 9) != 2 {
+		t.Errorf("Unexpected C1 info: %+v", info)
+	}
+	info, _ = fourcc.NV15.Info()
+	if info.BPP(0) != 10 || info.MinPitch(0, 1920) != 2400 ||
+		info.MinPitch(1, 1920) != 2400 {
+		t.Errorf("Unexpected NV15 info: %+v", info)
+	}
+	if _, ok := fourcc.Format(0x12345678).Info(); ok {
+		t.Errorf("Info of an unknown format")
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	for _, test := range []struct {
+		bpp, depth uint32
+		format     fourcc.Format
+	}{
+		{8, 8, fourcc.C8},
+		{16, 15, fourcc.XRGB1555},
+		{16, 16, fourcc.RGB565},
+		{24, 24, fourcc.RGB888},
+		{32, 24, fourcc.XRGB8888},
+		{32, 30, fourcc.XRGB2101010},
+		{32, 32, fourcc.ARGB8888},
+	} {
+		if format := fourcc.FromLegacy(test.bpp, test.depth); format != test.format {
+			t.Errorf("Expected %s for %d/%d but got %s", test.format,
+				test.bpp, test.depth, format)
+		}
+		bpp, depth, ok := test.format.Legacy()
+		if !ok || bpp != test.bpp || depth != test.depth {
+			t.Errorf("Unexpected legacy pair of %s: %d/%d", test.format, bpp, depth)
+		}
+	}
+	if format := fourcc.FromLegacy(32, 16); format != fourcc.Invalid {
+		t.Errorf("Expected no format for 32/16 but got %s", format)
+	}
+	if _, _, ok := fourcc.ARGB1555.Legacy(); ok {
+		t.Errorf("ARGB1555 has no legacy pair")
+	}
+	if format := fourcc.FromDepth(24); format != fourcc.XRGB8888 {
+		t.Errorf("Expected XRGB8888 for depth 24 but got %s", format)
+	}
+	if format := fourcc.FromDepth(30); format != fourcc.XRGB2101010 {
+		t.Errorf("Expected XRGB2101010 for depth 30 but got %s", format)
+	}
+}
@@ -0,0 +1,269 @@
+package fourcc
+
+// FormatInfo is the layout of a format, like the drm_format_info of
+// the kernel.
+type FormatInfo struct {
+	Format Format
+	Name   string
+
+	// Depth is the color depth of the format in the legacy (depth,
+	// bpp) pairs of mode.AddFB, zero if it has none.
+	Depth int
+
+	Planes int
+
+	// BytesPerBlock is the size of the blocks of pixels of each plane,
+	// of BlockWidth by BlockHeight pixels (zero meaning one). Most
+	// formats have blocks of one pixel; the formats only usable with
+	// modifiers have no block size.
+	BytesPerBlock [4]int
+	BlockWidth    [4]int
+	BlockHeight   [4]int
+
+	// HSub and VSub are the horizontal and vertical subsampling of the
+	// planes past the first, the chroma of the YCbCr formats.
+	HSub, VSub int
+
+	HasAlpha     bool
+	IsYUV        bool
+	ColorIndexed bool
+}
+
+// BPP returns the bits per pixel of the plane, zero for formats
+// without block size.
+func (info *FormatInfo) BPP(plane int) int {
+	return info.BytesPerBlock[plane] * 8 /
+		(info.blockWidth(plane) * info.blockHeight(plane))
+}
+
+// PlaneWidth returns the width in pixels of the plane of a width
+// pixels wide image.
+func (info *FormatInfo) PlaneWidth(plane, width int) int {
+	if plane == 0 {
+		return width
+	}
+	return (width + info.HSub - 1) / info.HSub
+}
+
+// PlaneHeight returns the height in pixels of the plane of a height
+// pixels high image.
+func (info *FormatInfo) PlaneHeight(plane, height int) int {
+	if plane == 0 {
+		return height
+	}
+	return (height + info.VSub - 1) / info.VSub
+}
+
+// MinPitch returns the minimum pitch, in bytes, of the plane of a
+// width pixels wide image.
+func (info *FormatInfo) MinPitch(plane, width int) int {
+	width = info.PlaneWidth(plane, width)
+	size := info.blockWidth(plane) * info.blockHeight(plane)
+	return (width*info.BytesPerBlock[plane] + size - 1) / size
+}
+
+func (info *FormatInfo) blockWidth(plane int) int {
+	if info.BlockWidth[plane] == 0 {
+		return 1
+	}
+	return info.BlockWidth[plane]
+}
+
+func (info *FormatInfo) blockHeight(plane int) int {
+	if info.BlockHeight[plane] == 0 {
+		return 1
+	}
+	return info.BlockHeight[plane]
+}
+
+// FromLegacy returns the format of a legacy (bpp, depth) pair, as the
+// kernel maps the framebuffers of mode.AddFB, or Invalid.
+func FromLegacy(bpp, depth uint32) Format {
+	switch {
+	case bpp == 8 && depth == 8:
+		return C8
+	case bpp == 16 && depth == 15:
+		return XRGB1555
+	case bpp == 16 && depth == 16:
+		return RGB565
+	case bpp == 24 && depth == 24:
+		return RGB888
+	case bpp == 32 && depth == 24:
+		return XRGB8888
+	case bpp == 32 && depth == 30:
+		return XRGB2101010
+	case bpp == 32 && depth == 32:
+		return ARGB8888
+	}
+	return Invalid
+}
+
+// FromDepth returns the format of the dumb buffers of a depth, like the
+// drm.CapDumbPreferredDepth of a driver, or Invalid.
+func FromDepth(depth uint32) Format {
+	switch depth {
+	case 8:
+		return FromLegacy(8, depth)
+	case 15, 16:
+		return FromLegacy(16, depth)
+	case 24, 30, 32:
+		return FromLegacy(32, depth)
+	}
+	return Invalid
+}
+
+// Legacy returns the legacy (bpp, depth) pair of the format, if it has
+// one.
+func (f Format) Legacy() (bpp, depth uint32, ok bool) {
+	info, ok := f.Info()
+	if !ok || info.Planes != 1 || info.Depth == 0 {
+		return 0, 0, false
+	}
+	bpp, depth = uint32(info.BPP(0)), uint32(info.Depth)
+	return bpp, depth, FromLegacy(bpp, depth) == f
+}
+
+var formats = make(map[Format]*FormatInfo)
+
+func init() {
+	for i := range formatTable {
+		formats[formatTable[i].Format] = &formatTable[i]
+	}
+}
+
+var formatTable = []FormatInfo{
+	{Format: C1, Name: "C1", Depth: 1, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{8}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1, ColorIndexed: true},
+	{Format: C2, Name: "C2", Depth: 2, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{4}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1, ColorIndexed: true},
+	{Format: C4, Name: "C4", Depth: 4, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{2}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1, ColorIndexed: true},
+	{Format: C8, Name: "C8", Depth: 8, Planes: 1, BytesPerBlock: [4]int{1}, HSub: 1, VSub: 1, ColorIndexed: true},
+	{Format: D1, Name: "D1", Depth: 1, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{8}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: D2, Name: "D2", Depth: 2, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{4}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: D4, Name: "D4", Depth: 4, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{2}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: D8, Name: "D8", Depth: 8, Planes: 1, BytesPerBlock: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: R1, Name: "R1", Depth: 1, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{8}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: R2, Name: "R2", Depth: 2, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{4}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: R4, Name: "R4", Depth: 4, Planes: 1, BytesPerBlock: [4]int{1}, BlockWidth: [4]int{2}, BlockHeight: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: R8, Name: "R8", Depth: 8, Planes: 1, BytesPerBlock: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: R10, Name: "R10", Depth: 10, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: R12, Name: "R12", Depth: 12, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: R16, Name: "R16", Depth: 16, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: RG88, Name: "RG88", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: GR88, Name: "GR88", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: RG1616, Name: "RG1616", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: GR1616, Name: "GR1616", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: RGB332, Name: "RGB332", Depth: 8, Planes: 1, BytesPerBlock: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: BGR233, Name: "BGR233", Depth: 8, Planes: 1, BytesPerBlock: [4]int{1}, HSub: 1, VSub: 1},
+	{Format: XRGB4444, Name: "XRGB4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: XBGR4444, Name: "XBGR4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: RGBX4444, Name: "RGBX4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: BGRX4444, Name: "BGRX4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: ARGB4444, Name: "ARGB4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR4444, Name: "ABGR4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGBA4444, Name: "RGBA4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGRA4444, Name: "BGRA4444", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: XRGB1555, Name: "XRGB1555", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: XBGR1555, Name: "XBGR1555", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: RGBX5551, Name: "RGBX5551", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: BGRX5551, Name: "BGRX5551", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: ARGB1555, Name: "ARGB1555", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR1555, Name: "ABGR1555", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGBA5551, Name: "RGBA5551", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGRA5551, Name: "BGRA5551", Depth: 15, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGB565, Name: "RGB565", Depth: 16, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: BGR565, Name: "BGR565", Depth: 16, Planes: 1, BytesPerBlock: [4]int{2}, HSub: 1, VSub: 1},
+	{Format: RGB888, Name: "RGB888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{3}, HSub: 1, VSub: 1},
+	{Format: BGR888, Name: "BGR888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{3}, HSub: 1, VSub: 1},
+	{Format: XRGB8888, Name: "XRGB8888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: XBGR8888, Name: "XBGR8888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: RGBX8888, Name: "RGBX8888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: BGRX8888, Name: "BGRX8888", Depth: 24, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: ARGB8888, Name: "ARGB8888", Depth: 32, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR8888, Name: "ABGR8888", Depth: 32, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGBA8888, Name: "RGBA8888", Depth: 32, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGRA8888, Name: "BGRA8888", Depth: 32, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: XRGB2101010, Name: "XRGB2101010", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: XBGR2101010, Name: "XBGR2101010", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: RGBX1010102, Name: "RGBX1010102", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: BGRX1010102, Name: "BGRX1010102", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1},
+	{Format: ARGB2101010, Name: "ARGB2101010", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR2101010, Name: "ABGR2101010", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGBA1010102, Name: "RGBA1010102", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGRA1010102, Name: "BGRA1010102", Depth: 30, Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: XRGB16161616, Name: "XRGB16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1},
+	{Format: XBGR16161616, Name: "XBGR16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1},
+	{Format: ARGB16161616, Name: "ARGB16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR16161616, Name: "ABGR16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: XRGB16161616F, Name: "XRGB16161616F", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1},
+	{Format: XBGR16161616F, Name: "XBGR16161616F", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1},
+	{Format: ARGB16161616F, Name: "ARGB16161616F", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: ABGR16161616F, Name: "ABGR16161616F", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: AXBXGXRX106106106106, Name: "AXBXGXRX106106106106", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: YUYV, Name: "YUYV", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: YVYU, Name: "YVYU", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: UYVY, Name: "UYVY", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: VYUY, Name: "VYUY", Planes: 1, BytesPerBlock: [4]int{2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: AYUV, Name: "AYUV", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true, IsYUV: true},
+	{Format: AVUY8888, Name: "AVUY8888", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true, IsYUV: true},
+	{Format: XYUV8888, Name: "XYUV8888", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: XVUY8888, Name: "XVUY8888", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: VUY888, Name: "VUY888", Planes: 1, BytesPerBlock: [4]int{3}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: VUY101010, Name: "VUY101010", Planes: 1, BytesPerBlock: [4]int{0}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: Y210, Name: "Y210", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: Y212, Name: "Y212", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: Y216, Name: "Y216", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: Y410, Name: "Y410", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, HasAlpha: true, IsYUV: true},
+	{Format: Y412, Name: "Y412", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true, IsYUV: true},
+	{Format: Y416, Name: "Y416", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, HasAlpha: true, IsYUV: true},
+	{Format: XVYU2101010, Name: "XVYU2101010", Planes: 1, BytesPerBlock: [4]int{4}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: XVYU12_16161616, Name: "XVYU12_16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: XVYU16161616, Name: "XVYU16161616", Planes: 1, BytesPerBlock: [4]int{8}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: Y0L0, Name: "Y0L0", Planes: 1, BytesPerBlock: [4]int{8}, BlockWidth: [4]int{2}, BlockHeight: [4]int{2}, HSub: 2, VSub: 2, HasAlpha: true, IsYUV: true},
+	{Format: X0L0, Name: "X0L0", Planes: 1, BytesPerBlock: [4]int{8}, BlockWidth: [4]int{2}, BlockHeight: [4]int{2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: Y0L2, Name: "Y0L2", Planes: 1, BytesPerBlock: [4]int{8}, BlockWidth: [4]int{2}, BlockHeight: [4]int{2}, HSub: 2, VSub: 2, HasAlpha: true, IsYUV: true},
+	{Format: X0L2, Name: "X0L2", Planes: 1, BytesPerBlock: [4]int{8}, BlockWidth: [4]int{2}, BlockHeight: [4]int{2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: YUV420_8BIT, Name: "YUV420_8BIT", Planes: 1, BytesPerBlock: [4]int{0}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: YUV420_10BIT, Name: "YUV420_10BIT", Planes: 1, BytesPerBlock: [4]int{0}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: XRGB8888_A8, Name: "XRGB8888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{4, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: XBGR8888_A8, Name: "XBGR8888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{4, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGBX8888_A8, Name: "RGBX8888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{4, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGRX8888_A8, Name: "BGRX8888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{4, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGB888_A8, Name: "RGB888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{3, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGR888_A8, Name: "BGR888_A8", Depth: 32, Planes: 2, BytesPerBlock: [4]int{3, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: RGB565_A8, Name: "RGB565_A8", Depth: 24, Planes: 2, BytesPerBlock: [4]int{2, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: BGR565_A8, Name: "BGR565_A8", Depth: 24, Planes: 2, BytesPerBlock: [4]int{2, 1}, HSub: 1, VSub: 1, HasAlpha: true},
+	{Format: NV12, Name: "NV12", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: NV21, Name: "NV21", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: NV16, Name: "NV16", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: NV61, Name: "NV61", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: NV24, Name: "NV24", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: NV42, Name: "NV42", Planes: 2, BytesPerBlock: [4]int{1, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: NV15, Name: "NV15", Planes: 2, BytesPerBlock: [4]int{5, 5}, BlockWidth: [4]int{4, 2}, BlockHeight: [4]int{1, 1}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: NV20, Name: "NV20", Planes: 2, BytesPerBlock: [4]int{5, 5}, BlockWidth: [4]int{4, 2}, BlockHeight: [4]int{1, 1}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: NV30, Name: "NV30", Planes: 2, BytesPerBlock: [4]int{5, 5}, BlockWidth: [4]int{4, 2}, BlockHeight: [4]int{1, 1}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: P210, Name: "P210", Planes: 2, BytesPerBlock: [4]int{2, 4}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: P010, Name: "P010", Planes: 2, BytesPerBlock: [4]int{2, 4}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: P012, Name: "P012", Planes: 2, BytesPerBlock: [4]int{2, 4}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: P016, Name: "P016", Planes: 2, BytesPerBlock: [4]int{2, 4}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: P030, Name: "P030", Planes: 2, BytesPerBlock: [4]int{4, 8}, BlockWidth: [4]int{3, 3}, BlockHeight: [4]int{1, 1}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: Q410, Name: "Q410", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: Q401, Name: "Q401", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: S010, Name: "S010", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: S210, Name: "S210", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: S410, Name: "S410", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: S012, Name: "S012", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: S212, Name: "S212", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: S412, Name: "S412", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: S016, Name: "S016", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: S216, Name: "S216", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: S416, Name: "S416", Planes: 3, BytesPerBlock: [4]int{2, 2, 2}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: YUV410, Name: "YUV410", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 4, VSub: 4, IsYUV: true},
+	{Format: YVU410, Name: "YVU410", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 4, VSub: 4, IsYUV: true},
+	{Format: YUV411, Name: "YUV411", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 4, VSub: 1, IsYUV: true},
+	{Format: YVU411, Name: "YVU411", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 4, VSub: 1, IsYUV: true},
+	{Format: YUV420, Name: "YUV420", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: YVU420, Name: "YVU420", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 2, VSub: 2, IsYUV: true},
+	{Format: YUV422, Name: "YUV422", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: YVU422, Name: "YVU422", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 2, VSub: 1, IsYUV: true},
+	{Format: YUV444, Name: "YUV444", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 1, VSub: 1, IsYUV: true},
+	{Format: YVU444, Name: "YVU444", Planes: 3, BytesPerBlock: [4]int{1, 1, 1}, HSub: 1, VSub: 1, IsYUV: true},
+}
@@ -0,0 +1,501 @@
+package fourcc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Modifier is a format modifier, the DRM_FORMAT_MOD_* codes of
+// drm_fourcc.h: the layout of the pixels of a framebuffer in memory,
+// as its tiling or compression, on top of its format. The top 8 bits
+// are the vendor of the layout, the other 56 bits its vendor specific
+// code.
+type Modifier uint64
+
+// Vendor is the vendor of a modifier layout.
+type Vendor uint8
+
+// Vendors of the modifiers
+const (
+	VendorNone Vendor = iota
+	VendorIntel
+	VendorAMD
+	VendorNVIDIA
+	VendorSamsung
+	VendorQcom
+	VendorVivante
+	VendorBroadcom
+	VendorARM
+	VendorAllwinner
+	VendorAmlogic
+	VendorMTK
+)
+
+var vendorNames = [...]string{
+	VendorNone:      "NONE",
+	VendorIntel:     "INTEL",
+	VendorAMD:       "AMD",
+	VendorNVIDIA:    "NVIDIA",
+	VendorSamsung:   "SAMSUNG",
+	VendorQcom:      "QCOM",
+	VendorVivante:   "VIVANTE",
+	VendorBroadcom:  "BROADCOM",
+	VendorARM:       "ARM",
+	VendorAllwinner: "ALLWINNER",
+	VendorAmlogic:   "AMLOGIC",
+	VendorMTK:       "MTK",
+}
+
+// String returns the name of the vendor, like "INTEL".
+func (v Vendor) String() string {
+	if int(v) < len(vendorNames) {
+		return vendorNames[v]
+	}
+	return fmt.Sprintf("%#02x", uint8(v))
+}
+
+const modValueMask = 1<<56 - 1
+
+// ModCode returns the modifier of the vendor with the code value.
+func ModCode(vendor Vendor, value uint64) Modifier {
+	return Modifier(vendor)<<56 | Modifier(value&modValueMask)
+}
+
+const (
+	// ModLinear is the layout of the framebuffers without modifier:
+	// the pixels in rows, the rows pitch bytes apart.
+	ModLinear Modifier = 0
+
+	// ModInvalid is the modifier of none, as reported for the
+	// framebuffers added without modifiers.
+	ModInvalid Modifier = modValueMask
+)
+
+const (
+	modIntel     = Modifier(VendorIntel) << 56
+	modNVIDIA    = Modifier(VendorNVIDIA) << 56
+	modSamsung   = Modifier(VendorSamsung) << 56
+	modQcom      = Modifier(VendorQcom) << 56
+	modVivante   = Modifier(VendorVivante) << 56
+	modBroadcom  = Modifier(VendorBroadcom) << 56
+	modARM       = Modifier(VendorARM) << 56
+	modAllwinner = Modifier(VendorAllwinner) << 56
+)
+
+const (
+	// Intel
+	ModI915XTiled             = modIntel | 1
+	ModI915YTiled             = modIntel | 2
+	ModI915YfTiled            = modIntel | 3
+	ModI915YTiledCCS          = modIntel | 4
+	ModI915YfTiledCCS         = modIntel | 5
+	ModI915YTiledGen12RCCCS   = modIntel | 6
+	ModI915YTiledGen12MCCCS   = modIntel | 7
+	ModI915YTiledGen12RCCCSCC = modIntel | 8
+	ModI915Tiled4             = modIntel | 9
+	ModI915Tiled4DG2RCCCS     = modIntel | 10
+	ModI915Tiled4DG2MCCCS     = modIntel | 11
+	ModI915Tiled4DG2RCCCSCC   = modIntel | 12
+	ModI915Tiled4MTLRCCCS     = modIntel | 13
+	ModI915Tiled4MTLMCCCS     = modIntel | 14
+	ModI915Tiled4MTLRCCCSCC   = modIntel | 15
+	ModI915Tiled4LNLCCS       = modIntel | 16
+	ModI915Tiled4BMGCCS       = modIntel | 17
+
+	// NVIDIA, see ModNVIDIABlockLinear2D
+	ModNVIDIATegraTiled = modNVIDIA | 1
+
+	// Samsung
+	ModSamsung64x32Tile = modSamsung | 1
+	ModSamsung16x16Tile = modSamsung | 2
+
+	// Qualcomm
+	ModQcomCompressed = modQcom | 1
+	ModQcomTiled2     = modQcom | 2
+	ModQcomTiled3     = modQcom | 3
+
+	// Vivante, optionally with tile status bits
+	ModVivanteTiled           = modVivante | 1
+	ModVivanteSuperTiled      = modVivante | 2
+	ModVivanteSplitTiled      = modVivante | 3
+	ModVivanteSplitSuperTiled = modVivante | 4
+
+	// Broadcom, see ModBroadcomSANDColHeight
+	ModBroadcomVC4TTiled = modBroadcom | 1
+	ModBroadcomSAND32    = modBroadcom | 2
+	ModBroadcomSAND64    = modBroadcom | 3
+	ModBroadcomSAND128   = modBroadcom | 4
+	ModBroadcomSAND256   = modBroadcom | 5
+	ModBroadcomUIF       = modBroadcom | 6
+
+	// ARM, see ModARMAFBC and ModARMAFRC
+	ModARM16x16BlockUInterleaved = modARM | armMisc<<armTypeShift | 1
+
+	// Allwinner
+	ModAllwinnerTiled = modAllwinner | 1
+)
+
+var modifierNames = map[Modifier]string{
+	ModLinear:                    "LINEAR",
+	ModInvalid:                   "INVALID",
+	ModI915XTiled:                "I915_X_TILED",
+	ModI915YTiled:                "I915_Y_TILED",
+	ModI915YfTiled:               "I915_Yf_TILED",
+	ModI915YTiledCCS:             "I915_Y_TILED_CCS",
+	ModI915YfTiledCCS:            "I915_Yf_TILED_CCS",
+	ModI915YTiledGen12RCCCS:      "I915_Y_TILED_GEN12_RC_CCS",
+	ModI915YTiledGen12MCCCS:      "I915_Y_TILED_GEN12_MC_CCS",
+	ModI915YTiledGen12RCCCSCC:    "I915_Y_TILED_GEN12_RC_CCS_CC",
+	ModI915Tiled4:                "I915_4_TILED",
+	ModI915Tiled4DG2RCCCS:        "I915_4_TILED_DG2_RC_CCS",
+	ModI915Tiled4DG2MCCCS:        "I915_4_TILED_DG2_MC_CCS",
+	ModI915Tiled4DG2RCCCSCC:      "I915_4_TILED_DG2_RC_CCS_CC",
+	ModI915Tiled4MTLRCCCS:        "I915_4_TILED_MTL_RC_CCS",
+	ModI915Tiled4MTLMCCCS:        "I915_4_TILED_MTL_MC_CCS",
+	ModI915Tiled4MTLRCCCSCC:      "I915_4_TILED_MTL_RC_CCS_CC",
+	ModI915Tiled4LNLCCS:          "I915_4_TILED_LNL_CCS",
+	ModI915Tiled4BMGCCS:          "I915_4_TILED_BMG_CCS",
+	ModNVIDIATegraTiled:          "NVIDIA_TEGRA_TILED",
+	ModSamsung64x32Tile:          "SAMSUNG_64_32_TILE",
+	ModSamsung16x16Tile:          "SAMSUNG_16_16_TILE",
+	ModQcomCompressed:            "QCOM_COMPRESSED",
+	ModQcomTiled2:                "QCOM_TILED2",
+	ModQcomTiled3:                "QCOM_TILED3",
+	ModVivanteTiled:              "VIVANTE_TILED",
+	ModVivanteSuperTiled:         "VIVANTE_SUPER_TILED",
+	ModVivanteSplitTiled:         "VIVANTE_SPLIT_TILED",
+	ModVivanteSplitSuperTiled:    "VIVANTE_SPLIT_SUPER_TILED",
+	ModBroadcomVC4TTiled:         "BROADCOM_VC4_T_TILED",
+	ModBroadcomSAND32:            "BROADCOM_SAND32",
+	ModBroadcomSAND64:            "BROADCOM_SAND64",
+	ModBroadcomSAND128:           "BROADCOM_SAND128",
+	ModBroadcomSAND256:           "BROADCOM_SAND256",
+	ModBroadcomUIF:               "BROADCOM_UIF",
+	ModARM16x16BlockUInterleaved: "ARM_16X16_BLOCK_U_INTERLEAVED",
+	ModAllwinnerTiled:            "ALLWINNER_TILED",
+}
+
+// Vendor returns the vendor of the modifier.
+func (m Modifier) Vendor() Vendor {
+	return Vendor(m >> 56)
+}
+
+// Value returns the vendor specific code of the modifier.
+func (m Modifier) Value() uint64 {
+	return uint64(m) & modValueMask
+}
+
+// String returns the name of the modifier as drm_info prints it, like
+// "I915_Y_TILED", with the parameters of the parameterized layouts,
+// like "ARM_AFBC(BLOCK_SIZE=16x16,YTR,SPARSE)". The unknown codes of a
+// vendor print as "VENDOR(0x...)".
+func (m Modifier) String() string {
+	if name, ok := modifierNames[m]; ok {
+		return name
+	}
+	var name string
+	switch m.Vendor() {
+	case VendorAMD:
+		name = amdString(m.Value())
+	case VendorNVIDIA:
+		name = nvidiaString(m.Value())
+	case VendorVivante:
+		name = vivanteString(m)
+	case VendorBroadcom:
+		name = broadcomString(m)
+	case VendorARM:
+		name = armString(m.Value())
+	case VendorAmlogic:
+		name = amlogicString(m.Value())
+	}
+	if name != "" {
+		return name
+	}
+	if int(m.Vendor()) >= len(vendorNames) {
+		return fmt.Sprintf("%#016x", uint64(m))
+	}
+	return fmt.Sprintf("%s(%#x)", m.Vendor(), m.Value())
+}
+
+// flagNames appends to params the names of the bits of value, from
+// the bit first, and the leftover bits in hex.
+func flagNames(params []string, value uint64, first uint, names []string) []string {
+	for i, name := range names {
+		bit := uint64(1) << (first + uint(i))
+		if value&bit != 0 {
+			params = append(params, name)
+			value &^= bit
+		}
+	}
+	if value != 0 {
+		params = append(params, fmt.Sprintf("%#x", value))
+	}
+	return params
+}
+
+// AMD modifiers are a set of fields of the tiling of the GFX IP
+// version of the GPU, and of its DCC compression.
+const (
+	amdTileVersionMask = 0xff
+	amdTileShift       = 8
+	amdTileMask        = 0x1f
+	amdDCCShift        = 13
+	amdFieldsShift     = 21
+
+	amdDCCMaxCompressedBlockShift = 18 - amdDCCShift
+)
+
+var (
+	amdTileVersions = map[uint64]string{
+		1: "GFX9",
+		2: "GFX10",
+		3: "GFX10_RBPLUS",
+		4: "GFX11",
+		5: "GFX12",
+	}
+	amdTiles = map[uint64]string{
+		9:  "GFX9_64K_S",
+		10: "GFX9_64K_D",
+		25: "GFX9_64K_S_X",
+		26: "GFX9_64K_D_X",
+		27: "GFX9_64K_R_X",
+		31: "GFX11_256K_R_X",
+	}
+	amdGFX12Tiles = map[uint64]string{
+		1: "GFX12_256B_2D",
+		2: "GFX12_4K_2D",
+		3: "GFX12_64K_2D",
+		4: "GFX12_256K_2D",
+	}
+	amdDCCFlags = []string{"DCC", "DCC_RETILE", "DCC_PIPE_ALIGN",
+		"DCC_INDEPENDENT_64B", "DCC_INDEPENDENT_128B"}
+	amdDCCMaxCompressedBlocks = []string{"64B", "128B", "256B", "3"}
+	amdFields                 = []string{"PIPE_XOR_BITS", "BANK_XOR_BITS",
+		"PACKERS", "RB", "PIPE"}
+)
+
+func amdString(value uint64) string {
+	version, ok := amdTileVersions[value&amdTileVersionMask]
+	if !ok {
+		return ""
+	}
+	tiles := amdTiles
+	if version == "GFX12" {
+		tiles = amdGFX12Tiles
+	}
+	tile := value >> amdTileShift & amdTileMask
+	var params []string
+	if name, ok := tiles[tile]; ok {
+		params = append(params, name)
+	} else {
+		params = append(params, fmt.Sprintf("TILE=%d", tile))
+	}
+
+	dcc := value >> amdDCCShift
+	if dcc&1 != 0 {
+		params = flagNames(params, value&(0x1f<<amdDCCShift), amdDCCShift,
+			amdDCCFlags)
+		params = append(params, "DCC_MAX_COMPRESSED_BLOCK="+
+			amdDCCMaxCompressedBlocks[dcc>>amdDCCMaxCompressedBlockShift&3])
+		if dcc>>(20-amdDCCShift)&1 != 0 {
+			params = append(params, "DCC_CONSTANT_ENCODE")
+		}
+	} else if unused := value & (0x7f << (amdDCCShift + 1)); unused != 0 {
+		// the DCC fields of a modifier without DCC
+		params = append(params, fmt.Sprintf("%#x", unused))
+	}
+	fields := value >> amdFieldsShift
+	for i, name := range amdFields {
+		if field := fields >> uint(3*i) & 7; field != 0 {
+			params = append(params, fmt.Sprintf("%s=%d", name, field))
+		}
+	}
+	if rest := fields >> uint(3*len(amdFields)); rest != 0 {
+		params = append(params, fmt.Sprintf("%#x", rest<<(amdFieldsShift+15)))
+	}
+	return "AMD_" + version + "(" + strings.Join(params, ",") + ")"
+}
+
+// NVIDIA block linear layout fields
+const (
+	nvidiaBlockLinear     = 0x10
+	nvidiaBlockLinearMask = 0x3fff01f
+)
+
+// ModNVIDIABlockLinear2D returns the NVIDIA 2D block linear layout of
+// blocks of 2^h GOBs (groups of bytes) high, with the page kind k, GOB
+// height and kind generation g, sector layout s and compression c.
+func ModNVIDIABlockLinear2D(c, s, g, k, h uint64) Modifier {
+	return ModCode(VendorNVIDIA, nvidiaBlockLinear|h&0xf|(k&0xff)<<12|
+		(g&3)<<20|(s&1)<<22|(c&7)<<23)
+}
+
+func nvidiaString(value uint64) string {
+	if value&nvidiaBlockLinear == 0 || value&^nvidiaBlockLinearMask != 0 {
+		return ""
+	}
+	return fmt.Sprintf("NVIDIA_BLOCK_LINEAR_2D(h=%d,k=%#x,g=%d,s=%d,c=%d)",
+		value&0xf, value>>12&0xff, value>>20&3, value>>22&1, value>>23&7)
+}
+
+// Vivante tile status bits
+const (
+	vivanteTSShift   = 48
+	vivanteCompShift = 52
+	vivanteExtMask   = 0xff << vivanteTSShift
+)
+
+var (
+	vivanteTS   = []string{1: "64_4", 2: "64_2", 3: "128_4", 4: "256_4"}
+	vivanteComp = []string{1: "DEC400"}
+)
+
+func vivanteString(m Modifier) string {
+	name, ok := modifierNames[m&^vivanteExtMask]
+	if !ok {
+		return ""
+	}
+	var params []string
+	ts := m.Value() >> vivanteTSShift & 0xf
+	comp := m.Value() >> vivanteCompShift & 0xf
+	if ts != 0 {
+		params = append(params, "TS="+enumName(vivanteTS, ts))
+	}
+	if comp != 0 {
+		params = append(params, "COMP="+enumName(vivanteComp, comp))
+	}
+	return name + "(" + strings.Join(params, ",") + ")"
+}
+
+// enumName returns names[value], or value if it has no name.
+func enumName(names []string, value uint64) string {
+	if value < uint64(len(names)) && names[value] != "" {
+		return names[value]
+	}
+	return fmt.Sprint(value)
+}
+
+const broadcomParamShift = 8
+
+// ModBroadcomSANDColHeight returns the Broadcom SAND layout sand
+// (ModBroadcomSAND32 to ModBroadcomSAND256) with columns of height
+// rows.
+func ModBroadcomSANDColHeight(sand Modifier, height uint64) Modifier {
+	return ModCode(VendorBroadcom, sand.Value()&0xff|height<<broadcomParamShift)
+}
+
+func broadcomString(m Modifier) string {
+	height := m.Value() >> broadcomParamShift
+	if m&0xff < ModBroadcomSAND32&0xff || m&0xff > ModBroadcomSAND256&0xff {
+		return ""
+	}
+	return fmt.Sprintf("%s(COL_HEIGHT=%d)", modifierNames[modBroadcom|m&0xff],
+		height)
+}
+
+// ARM modifier types
+const (
+	armAFBC      = 0
+	armMisc      = 1
+	armAFRC      = 2
+	armTypeShift = 52
+	armValueMask = 1<<armTypeShift - 1
+)
+
+// Flags of ModARMAFBC: one of the block sizes, and the features
+const (
+	AFBCBlockSize16x16     = 1
+	AFBCBlockSize32x8      = 2
+	AFBCBlockSize64x4      = 3
+	AFBCBlockSize32x8_64x4 = 4
+
+	AFBCYTR    = 1 << 4  // YUV transform
+	AFBCSplit  = 1 << 5  // split blocks
+	AFBCSparse = 1 << 6  // sparse layout
+	AFBCCBR    = 1 << 7  // copy blocks restrict
+	AFBCTiled  = 1 << 8  // tiled layout
+	AFBCSC     = 1 << 9  // solid color blocks
+	AFBCDB     = 1 << 10 // double buffer
+	AFBCBCH    = 1 << 11 // buffer content hints
+	AFBCUSM    = 1 << 12 // uncompressed storage mode
+)
+
+// Flags of ModARMAFRC: the coding unit sizes of the first plane and
+// of the others, and the layout
+const (
+	AFRCCUSize16 = 1
+	AFRCCUSize24 = 2
+	AFRCCUSize32 = 3
+
+	AFRCCUSizeP12Shift = 4
+	AFRCLayoutScan     = 1 << 8 // scan layout instead of rotation layout
+)
+
+var (
+	afbcBlockSizes = []string{1: "16x16", 2: "32x8", 3: "64x4", 4: "32x8_64x4"}
+	afbcFlags      = []string{"YTR", "SPLIT", "SPARSE", "CBR", "TILED", "SC",
+		"DB", "BCH", "USM"}
+	afrcCUSizes = []string{1: "16", 2: "24", 3: "32"}
+)
+
+// ModARMAFBC returns the ARM frame buffer compression layout with the
+// AFBC flags.
+func ModARMAFBC(flags uint64) Modifier {
+	return modARM | armAFBC<<armTypeShift | Modifier(flags&armValueMask)
+}
+
+// ModARMAFRC returns the ARM fixed rate compression layout with the
+// AFRC flags.
+func ModARMAFRC(flags uint64) Modifier {
+	return modARM | armAFRC<<armTypeShift | Modifier(flags&armValueMask)
+}
+
+func armString(value uint64) string {
+	flags := value & armValueMask
+	switch value >> armTypeShift {
+	case armAFBC:
+		params := []string{"BLOCK_SIZE=" + enumName(afbcBlockSizes, flags&0xf)}
+		params = flagNames(params, flags&^0xf, 4, afbcFlags)
+		return "ARM_AFBC(" + strings.Join(params, ",") + ")"
+	case armAFRC:
+		params := []string{
+			"CU_SIZE_P0=" + enumName(afrcCUSizes, flags&0xf),
+			"CU_SIZE_P12=" + enumName(afrcCUSizes, flags>>AFRCCUSizeP12Shift&0xf),
+		}
+		if flags&AFRCLayoutScan != 0 {
+			params = append(params, "SCAN")
+		} else {
+			params = append(params, "ROT")
+		}
+		if rest := flags >> 9; rest != 0 {
+			params = append(params, fmt.Sprintf("%#x", rest<<9))
+		}
+		return "ARM_AFRC(" + strings.Join(params, ",") + ")"
+	}
+	return ""
+}
+
+// Layouts and options of ModAmlogicFBC
+const (
+	AmlogicFBCLayoutBasic   = 1
+	AmlogicFBCLayoutScatter = 2
+
+	AmlogicFBCMemSaving = 1
+)
+
+var amlogicLayouts = []string{1: "BASIC", 2: "SCATTER"}
+
+// ModAmlogicFBC returns the Amlogic frame buffer compression layout
+// with the options.
+func ModAmlogicFBC(layout, options uint64) Modifier {
+	return ModCode(VendorAmlogic, layout&0xff|(options&0xff)<<8)
+}
+
+func amlogicString(value uint64) string {
+	if value>>16 != 0 || value&0xff == 0 {
+		return ""
+	}
+	params := []string{"LAYOUT=" + enumName(amlogicLayouts, value&0xff)}
+	params = flagNames(params, value&^0xff, 8, []string{"MEM_SAVING"})
+	return "AMLOGIC_FBC(" + strings.Join(params, ",") + ")"
+}
@@ -0,0 +1,98 @@
+package fourcc_test
+
+import (
+	"testing"
+
+	"github.com/NeowayLabs/drm/fourcc"
+)
+
+func TestModifierString(t *testing.T) {
+	for _, test := range []struct {
+		mod  fourcc.Modifier
+		code uint64
+		name string
+	}{
+		{fourcc.ModLinear, 0, "LINEAR"},
+		{fourcc.ModInvalid, 0x00ffffffffffffff, "INVALID"},
+		{fourcc.ModI915YTiledCCS, 0x0100000000000004, "I915_Y_TILED_CCS"},
+		{fourcc.ModI915Tiled4, 0x0100000000000009, "I915_4_TILED"},
+		{fourcc.ModBroadcomUIF, 0x0700000000000006, "BROADCOM_UIF"},
+		{
+			fourcc.ModARMAFBC(fourcc.AFBCBlockSize16x16 | fourcc.AFBCYTR |
+				fourcc.AFBCSparse),
+			0x0800000000000051, "ARM_AFBC(BLOCK_SIZE=16x16,YTR,SPARSE)",
+		},
+		{
+			fourcc.ModARMAFBC(fourcc.AFBCBlockSize32x8 | 1<<20),
+			0x0800000000100002, "ARM_AFBC(BLOCK_SIZE=32x8,0x100000)",
+		},
+		{
+			fourcc.ModARMAFRC(fourcc.AFRCCUSize16 |
+				fourcc.AFRCCUSize24<<fourcc.AFRCCUSizeP12Shift |
+				fourcc.AFRCLayoutScan),
+			0x0820000000000121, "ARM_AFRC(CU_SIZE_P0=16,CU_SIZE_P12=24,SCAN)",
+		},
+		{fourcc.ModARM16x16BlockUInterleaved, 0x0810000000000001,
+			"ARM_16X16_BLOCK_U_INTERLEAVED"},
+		{
+			fourcc.ModBroadcomSANDColHeight(fourcc.ModBroadcomSAND128, 96),
+			0x0700000000006004, "BROADCOM_SAND128(COL_HEIGHT=96)",
+		},
+		{
+			fourcc.ModNVIDIABlockLinear2D(0, 1, 2, 0xfe, 4),
+			0x03000000006fe014, "NVIDIA_BLOCK_LINEAR_2D(h=4,k=0xfe,g=2,s=1,c=0)",
+		},
+		{
+			// GFX9_64K_S_X tiles, with DCC, PIPE_XOR_BITS=3
+			fourcc.Modifier(0x0200000000613901), 0x0200000000613901,
+			"AMD_GFX9(GFX9_64K_S_X,DCC,DCC_INDEPENDENT_64B," +
+				"DCC_MAX_COMPRESSED_BLOCK=64B,PIPE_XOR_BITS=3)",
+		},
+		{
+			// DCC_INDEPENDENT_64B without DCC
+			fourcc.Modifier(0x0200000000011901), 0x0200000000011901,
+			"AMD_GFX9(GFX9_64K_S_X,0x10000)",
+		},
+		{
+			fourcc.Modifier(0x0200000018801f04), 0x0200000018801f04,
+			"AMD_GFX11(GFX11_256K_R_X,PIPE_XOR_BITS=4,PACKERS=3)",
+		},
+		{fourcc.ModVivanteSuperTiled | 1<<48, 0x0601000000000002,
+			"VIVANTE_SUPER_TILED(TS=64_4)"},
+		{
+			fourcc.ModAmlogicFBC(fourcc.AmlogicFBCLayoutScatter,
+				fourcc.AmlogicFBCMemSaving),
+			0x0a00000000000102, "AMLOGIC_FBC(LAYOUT=SCATTER,MEM_SAVING)",
+		},
+		{fourcc.ModCode(fourcc.VendorMTK, 0x101), 0x0b00000000000101, "MTK(0x101)"},
+		{fourcc.ModCode(fourcc.VendorIntel, 0x42), 0x0100000000000042, "INTEL(0x42)"},
+		{fourcc.Modifier(0x2a00000000000001), 0x2a00000000000001, "0x2a00000000000001"},
+	} {
+		if uint64(test.mod) != test.code {
+			t.Errorf("Expected %s to be %#016x but got %#016x",
+				test.name, test.code, uint64(test.mod))
+		}
+		if name := test.mod.String(); name != test.name {
+			t.Errorf("Expected name %q but got %q", test.name, name)
+		}
+	}
+}
+
+func TestModifierVendor(t *testing.T) {
+	if v := fourcc.ModI915XTiled.Vendor(); v != fourcc.VendorIntel || v.String() != "INTEL" {
+		t.Errorf("Unexpected vendor %v", v)
+	}
+	if val := fourcc.ModI915XTiled.Value(); val != 1 {
+		t.Errorf("Unexpected value %#x", val)
+	}
+	if v := fourcc.ModInvalid.Vendor(); v != fourcc.VendorNone {
+		t.Errorf("Unexpected vendor of INVALID %v", v)
+	}
+	mod := fourcc.ModBroadcomSANDColHeight(fourcc.ModBroadcomSAND256, 128)
+	if mod.Vendor() != fourcc.VendorBroadcom || mod.Value() != 128<<8|5 {
+		t.Errorf("Unexpected SAND256 modifier %#x", uint64(mod))
+	}
+	if v := fourcc.Vendor(0x2a).String(); v != "0x2a" {
+		t.Errorf("Unexpected unknown vendor %q", v)
+	}
+}
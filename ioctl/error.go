@@ -0,0 +1,114 @@
+package ioctl
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+)
+
+type (
+	// Error is the error of a failed ioctl request.
+	Error struct {
+		Name  string        // name of the request, if registered
+		Code  Code          // request code
+		Errno syscall.Errno // error set by the device
+
+		// Err is the meaning of Errno for this request, as registered
+		// by RegisterErrors (eg.: drm.ErrNotMaster). It may be nil.
+		Err error
+	}
+
+	request struct {
+		name string
+		errs map[syscall.Errno]error
+	}
+)
+
+var (
+	requestsMu sync.RWMutex
+	requests   = make(map[uint32]*request)
+)
+
+func (e *Error) Error() string {
+	name := e.Name
+	if name == "" {
+		name = e.Code.String()
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("ioctl %s: %s (%s)", name, e.Err, e.Errno)
+	}
+	return fmt.Sprintf("ioctl %s: %s", name, e.Errno)
+}
+
+// Unwrap makes errors.Is and errors.As match both Err and Errno.
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Err, e.Errno}
+	}
+	return []error{e.Errno}
+}
+
+func lookup(code uint32) *request {
+	req, ok := requests[code]
+	if !ok {
+		req = &request{}
+		requests[code] = req
+	}
+	return req
+}
+
+// Register names the request code in errors. It returns the code, so
+// requests can be registered where they are declared:
+//
+//	IOCTLVersion = ioctl.Register("DRM_IOCTL_VERSION",
+//		ioctl.IOWR(IOCTLBase, 0x00, version{}))
+func Register(name string, code uint32) uint32 {
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+	lookup(code).name = name
+	return code
+}
+
+// RegisterErrors sets the errors meant by the errno values returned by
+// the request code. They are reported in Error.Err.
+func RegisterErrors(code uint32, errs map[syscall.Errno]error) {
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+	req := lookup(code)
+	if req.errs == nil {
+		req.errs = make(map[syscall.Errno]error)
+	}
+	for errno, err := range errs {
+		req.errs[errno] = err
+	}
+}
+
+// Name returns the registered name of the request code, or its C macro
+// if it was not registered.
+func Name(code uint32) string {
+	requestsMu.RLock()
+	defer requestsMu.RUnlock()
+	if req, ok := requests[code]; ok && req.name != "" {
+		return req.name
+	}
+	return Decode(code).String()
+}
+
+func wrapError(cmd uintptr, err error) error {
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		return err
+	}
+
+	ioerr := &Error{
+		Code:  Decode(uint32(cmd)),
+		Errno: errno,
+	}
+	requestsMu.RLock()
+	if req, ok := requests[uint32(cmd)]; ok {
+		ioerr.Name = req.name
+		ioerr.Err = req.errs[errno]
+	}
+	requestsMu.RUnlock()
+	return ioerr
+}
@@ -2,6 +2,8 @@ package ioctl
 
 import (
 	"fmt"
+	"reflect"
+	"sync/atomic"
 	"syscall"
 )
 
@@ -10,6 +12,8 @@ import (
 // Most architectures use this generic format, but check
 // include/ARCH/ioctl.h for specifics, e.g. powerpc
 // uses 3 bits to encode read/write and 13 bits for size.
+// The layout of the target architecture is selected by build
+// tags, see layout.go.
 //
 //  bits    meaning
 //  31-30	00 - no parameters: uses _IO macro
@@ -30,40 +34,191 @@ import (
 // #define VFAT_IOCTL_READDIR_BOTH         _IOR('r', 1, struct dirent [2])
 // source: https://www.kernel.org/doc/Documentation/ioctl/ioctl-decoding.txt
 
+// Code is a decoded ioctl request code.
 type Code struct {
 	typ  uint8  // type of ioctl call (read, write, both or none)
-	sz   uint16 // size of arguments (only 13bits usable)
+	sz   uint16 // size of arguments (13 or 14 bits, depending on arch)
 	uniq uint8  // unique ascii character for this device
 	fn   uint8  // function code
 }
 
-const (
-	None  = uint8(0x0)
-	Write = uint8(0x1)
-	Read  = uint8(0x2)
-)
-
 func NewCode(typ uint8, sz uint16, uniq, fn uint8) uint32 {
-	var code uint32
-	if typ > Write|Read {
-		panic(fmt.Errorf("invalid ioctl code value: %d\n", typ))
-	}
+	return native.encode(typ, sz, uniq, fn)
+}
+
+// Decode splits an ioctl request code into its fields.
+func Decode(code uint32) Code {
+	return native.decode(code)
+}
+
+// IO returns the code of a request without parameters.
+func IO(typ, nr uint8) uint32 {
+	return NewCode(None, 0, typ, nr)
+}
+
+// IOR returns the code of a request reading (from the device) a value
+// of the same type as sample.
+func IOR(typ, nr uint8, sample interface{}) uint32 {
+	return NewCode(Read, sizeOf(sample), typ, nr)
+}
+
+// IOW returns the code of a request writing (to the device) a value of
+// the same type as sample.
+func IOW(typ, nr uint8, sample interface{}) uint32 {
+	return NewCode(Write, sizeOf(sample), typ, nr)
+}
+
+// IOWR returns the code of a request reading and writing a value of
+// the same type as sample.
+func IOWR(typ, nr uint8, sample interface{}) uint32 {
+	return NewCode(Read|Write, sizeOf(sample), typ, nr)
+}
 
-	if sz > 2<<14 {
+func sizeOf(sample interface{}) uint16 {
+	sz := reflect.TypeOf(sample).Size()
+	if sz > 0xffff {
 		panic(fmt.Errorf("invalid ioctl size value: %d\n", sz))
 	}
+	return uint16(sz)
+}
+
+// Dir returns the direction of the request: None, Read, Write or
+// Read|Write.
+func (c Code) Dir() uint8 { return c.typ }
+
+// Size returns the size of the request argument.
+func (c Code) Size() uint16 { return c.sz }
+
+// Type returns the ascii character unique to the driver.
+func (c Code) Type() uint8 { return c.uniq }
 
-	code = code | (uint32(typ) << 30)
-	code = code | (uint32(sz) << 16) // sz has 14bits
-	code = code | (uint32(uniq) << 8)
-	code = code | uint32(fn)
-	return code
+// Nr returns the function number.
+func (c Code) Nr() uint8 { return c.fn }
+
+// Uint32 returns the encoded request code.
+func (c Code) Uint32() uint32 {
+	return NewCode(c.typ, c.sz, c.uniq, c.fn)
 }
 
+// String returns the code as the C macro defining it,
+// eg.: _IOWR('d', 0xA2, 104)
+func (c Code) String() string {
+	var macro string
+	switch c.typ {
+	case None:
+		return fmt.Sprintf("_IO(%s, 0x%02X)", quoteType(c.uniq), c.fn)
+	case Read:
+		macro = "_IOR"
+	case Write:
+		macro = "_IOW"
+	case Read | Write:
+		macro = "_IOWR"
+	}
+	return fmt.Sprintf("%s(%s, 0x%02X, %d)", macro, quoteType(c.uniq),
+		c.fn, c.sz)
+}
+
+func quoteType(typ uint8) string {
+	if typ >= 0x20 && typ < 0x7f && typ != '\'' && typ != '\\' {
+		return fmt.Sprintf("'%c'", typ)
+	}
+	return fmt.Sprintf("0x%02X", typ)
+}
+
+type (
+	// File is an open file on which ioctl requests can be issued.
+	// Both *os.File and *drm.Device implement it.
+	File interface {
+		Fd() uintptr
+	}
+
+	// Doer issues ioctl requests. It returns the syscall.Errno set by
+	// the request, if any. Kernel is the Doer used by default, fake
+	// devices implement it to run without the real hardware.
+	Doer interface {
+		Do(fd, cmd, ptr uintptr) error
+	}
+
+	// DoerFunc adapts an ordinary function to the Doer interface.
+	DoerFunc func(fd, cmd, ptr uintptr) error
+
+	// Retry is the policy to restart requests interrupted by a signal
+	// (EINTR) or asking to be tried again (EAGAIN), like libdrm's
+	// drmIoctl does.
+	Retry struct {
+		// Max is the maximum number of restarts, zero means no limit.
+		Max int
+	}
+)
+
+// Kernel issues requests with the ioctl system call.
+var Kernel Doer = DoerFunc(sysIoctl)
+
+// defaultRetry is the policy used by Do and Call, a *Retry.
+var defaultRetry atomic.Pointer[Retry]
+
+func init() {
+	defaultRetry.Store(&Retry{})
+}
+
+// DefaultRetry returns the policy used by Do and Call.
+func DefaultRetry() Retry {
+	return *defaultRetry.Load()
+}
+
+// SetDefaultRetry sets the policy used by Do and Call, returning the
+// previous one. It is safe to call while requests are being issued.
+func SetDefaultRetry(r Retry) Retry {
+	return *defaultRetry.Swap(&r)
+}
+
+// Do issues the request through doer, restarting it as allowed by the
+// policy. The error of the last try is returned.
+func (r Retry) Do(doer Doer, fd, cmd, ptr uintptr) error {
+	for retries := 0; ; retries++ {
+		err := doer.Do(fd, cmd, ptr)
+		if err != syscall.EINTR && err != syscall.EAGAIN {
+			return err
+		}
+		if r.Max > 0 && retries >= r.Max {
+			return err
+		}
+	}
+}
+
+func (f DoerFunc) Do(fd, cmd, ptr uintptr) error {
+	return f(fd, cmd, ptr)
+}
+
+// Do issues the request cmd on fd with the ioctl system call,
+// restarting it as DefaultRetry allows. Errors are of type *Error.
 func Do(fd, cmd, ptr uintptr) error {
+	return DoRetry(fd, cmd, ptr, DefaultRetry())
+}
+
+// DoRetry is like Do but restarts the request following the policy r.
+func DoRetry(fd, cmd, ptr uintptr, r Retry) error {
+	return wrapError(cmd, r.Do(Kernel, fd, cmd, ptr))
+}
+
+func sysIoctl(fd, cmd, ptr uintptr) error {
 	_, _, errcode := syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, ptr)
 	if errcode != 0 {
 		return errcode
 	}
 	return nil
 }
+
+// Call issues the request cmd on file. Files having their own Doer,
+// returned by a Doer() method (eg.: drm.Device), route the request
+// through it, any other file goes straight to the Kernel. Requests are
+// restarted as DefaultRetry allows. Errors are of type *Error.
+func Call(file File, cmd, ptr uintptr) error {
+	doer := Kernel
+	if f, ok := file.(interface {
+		Doer() Doer
+	}); ok {
+		doer = f.Doer()
+	}
+	return wrapError(cmd, DefaultRetry().Do(doer, file.Fd(), cmd, ptr))
+}
@@ -1,7 +1,10 @@
 package ioctl
 
 import (
+	"errors"
+	"runtime"
 	"strconv"
+	"syscall"
 	"testing"
 )
 
@@ -18,3 +21,278 @@ func TestNewCode(t *testing.T) {
 		return
 	}
 }
+
+type drmModeCrtc struct {
+	setConnectorsPtr uint64
+	countConnectors  uint32
+	crtcID, fbID     uint32
+	x, y             uint32
+	gammaSize        uint32
+	modeValid        uint32
+	mode             [68]byte
+}
+
+func TestHelpers(t *testing.T) {
+	for _, test := range []struct {
+		code     uint32
+		expected uint32
+		str      string
+	}{
+		{IO('d', 0x1e), 0x641e, "_IO('d', 0x1E)"},
+		{IOR('d', 0x02, uint32(0)), 0x80046402, "_IOR('d', 0x02, 4)"},
+		{IOW('d', 0x0d, [2]uint64{}), 0x4010640d, "_IOW('d', 0x0D, 16)"},
+		{IOWR('d', 0xA2, drmModeCrtc{}), 0xc06864a2, "_IOWR('d', 0xA2, 104)"},
+		{IOR('r', 1, [0x218]byte{}), 0x82187201, "_IOR('r', 0x01, 536)"},
+	} {
+		if test.code != test.expected {
+			t.Errorf("Expected %s but got %s", getbits(test.expected),
+				getbits(test.code))
+		}
+		code := Decode(test.code)
+		if str := code.String(); str != test.str {
+			t.Errorf("Expected %s but got %s", test.str, str)
+		}
+		if code.Uint32() != test.code {
+			t.Errorf("Expected %s but got %s", getbits(test.code),
+				getbits(code.Uint32()))
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	code := Decode(0xc06864a2)
+	if code.Dir() != Read|Write || code.Size() != 104 ||
+		code.Type() != 'd' || code.Nr() != 0xA2 {
+		t.Errorf("Unexpected decoded code: %#v", code)
+	}
+}
+
+// layouts are the ioctl code encodings by GOARCH, plus sparc and alpha,
+// which Go does not support, checked against the codes of their kernel.
+var layouts = map[string]layout{
+	"386":      genericLayout,
+	"amd64":    genericLayout,
+	"arm":      genericLayout,
+	"arm64":    genericLayout,
+	"loong64":  genericLayout,
+	"riscv64":  genericLayout,
+	"s390x":    genericLayout,
+	"ppc":      powerpcLayout,
+	"ppc64":    powerpcLayout,
+	"ppc64le":  powerpcLayout,
+	"mips":     mipsLayout,
+	"mipsle":   mipsLayout,
+	"mips64":   mipsLayout,
+	"mips64le": mipsLayout,
+	"sparc64":  {sizeBits: 13, none: 1, read: 2, write: 4},
+	"alpha":    {sizeBits: 13, none: 1, read: 2, write: 4},
+}
+
+// drmVersion is struct drm_version: 3 ints, then 3 size_t and 3
+// pointers, as wide as the architecture.
+type drmVersion struct {
+	major, minor, patch int32
+	namelen             uint
+	name                uintptr
+	datelen             uint
+	date                uintptr
+	desclen             uint
+	desc                uintptr
+}
+
+// TestArchLayouts checks the layouts against the DRM codes of each
+// architecture, and the codes computed by the package against the
+// codes of the architecture it runs on. Run it under each GOARCH,
+// see the cross target of the Makefile.
+func TestArchLayouts(t *testing.T) {
+	// known DRM codes, as printed by the C macros of each arch
+	type drmCodes struct {
+		version      uint32 // _IOWR('d', 0x00, struct drm_version)
+		setMaster    uint32 // _IO('d', 0x1e)
+		getMagic     uint32 // _IOR('d', 0x02, struct drm_auth)
+		setClientCap uint32 // _IOW('d', 0x0d, struct drm_set_client_cap)
+		setCrtc      uint32 // _IOWR('d', 0xA2, struct drm_mode_crtc)
+	}
+	var (
+		generic64 = drmCodes{0xc0406400, 0x0000641e, 0x80046402, 0x4010640d, 0xc06864a2}
+		generic32 = drmCodes{0xc0246400, 0x0000641e, 0x80046402, 0x4010640d, 0xc06864a2}
+		other64   = drmCodes{0xc0406400, 0x2000641e, 0x40046402, 0x8010640d, 0xc06864a2}
+		other32   = drmCodes{0xc0246400, 0x2000641e, 0x40046402, 0x8010640d, 0xc06864a2}
+	)
+
+	var native bool
+	for _, test := range []struct {
+		arch     string
+		ptrSize  uint16
+		expected drmCodes
+	}{
+		{"386", 4, generic32},
+		{"amd64", 8, generic64},
+		{"arm", 4, generic32},
+		{"arm64", 8, generic64},
+		{"loong64", 8, generic64},
+		{"riscv64", 8, generic64},
+		{"s390x", 8, generic64},
+		{"ppc64", 8, other64},
+		{"ppc64le", 8, other64},
+		{"mips", 4, other32},
+		{"mipsle", 4, other32},
+		{"mips64", 8, other64},
+		{"mips64le", 8, other64},
+		{"sparc64", 8, other64},
+		{"alpha", 8, other64},
+	} {
+		l, ok := layouts[test.arch]
+		if !ok {
+			t.Errorf("%s: no layout", test.arch)
+			continue
+		}
+		// struct drm_version has 3 ints, 3 size_t and 3 pointers
+		versionSize := 3*4 + 6*test.ptrSize
+		if test.ptrSize == 8 {
+			versionSize += 4 // padding
+		}
+		got := drmCodes{
+			version:      l.encode(l.read|l.write, versionSize, 'd', 0x00),
+			setMaster:    l.encode(l.none, 0, 'd', 0x1e),
+			getMagic:     l.encode(l.read, 4, 'd', 0x02),
+			setClientCap: l.encode(l.write, 16, 'd', 0x0d),
+			setCrtc:      l.encode(l.read|l.write, 104, 'd', 0xA2),
+		}
+		if got != test.expected {
+			t.Errorf("%s: expected %#x but got %#x", test.arch,
+				test.expected, got)
+		}
+		for _, code := range []uint32{got.version, got.setMaster,
+			got.getMagic, got.setClientCap, got.setCrtc} {
+			if decoded := l.decode(code); l.encode(decoded.typ,
+				decoded.sz, decoded.uniq, decoded.fn) != code {
+				t.Errorf("%s: %#x decoded as %#v", test.arch, code,
+					decoded)
+			}
+		}
+
+		if test.arch != runtime.GOARCH {
+			continue
+		}
+		native = true
+		got = drmCodes{
+			version:      IOWR('d', 0x00, drmVersion{}),
+			setMaster:    IO('d', 0x1e),
+			getMagic:     IOR('d', 0x02, uint32(0)),
+			setClientCap: IOW('d', 0x0d, [2]uint64{}),
+			setCrtc:      IOWR('d', 0xA2, drmModeCrtc{}),
+		}
+		if got != test.expected {
+			t.Errorf("%s: expected native codes %#x but got %#x",
+				test.arch, test.expected, got)
+		}
+	}
+	if !native {
+		t.Errorf("No DRM codes known for %s", runtime.GOARCH)
+	}
+}
+
+func TestNativeLayout(t *testing.T) {
+	l, ok := layouts[runtime.GOARCH]
+	if !ok {
+		t.Fatalf("no layout for %s", runtime.GOARCH)
+	}
+	if l != native || l.none != None || l.read != Read || l.write != Write {
+		t.Errorf("Build tags selected the wrong layout for %s",
+			runtime.GOARCH)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for too big size")
+		}
+	}()
+	NewCode(Read, 1<<native.sizeBits, 'd', 0)
+}
+
+func TestError(t *testing.T) {
+	var (
+		errFoo = errors.New("foo")
+		code   = Register("TEST_FOO", IOW('t', 0x01, uint32(0)))
+	)
+	RegisterErrors(code, map[syscall.Errno]error{syscall.EBUSY: errFoo})
+
+	fail := DoerFunc(func(fd, cmd, ptr uintptr) error {
+		return syscall.EBUSY
+	})
+	err := Call(fakeFile{fail}, uintptr(code), 0)
+	if !errors.Is(err, errFoo) || !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("Unexpected error chain: %v", err)
+	}
+	if expected := "ioctl TEST_FOO: foo (device or resource busy)"; err.Error() != expected {
+		t.Errorf("Expected %q but got %q", expected, err.Error())
+	}
+
+	unknown := IOR('t', 0x02, uint64(0))
+	err = Call(fakeFile{fail}, uintptr(unknown), 0)
+	if expected := "ioctl _IOR('t', 0x02, 8): device or resource busy"; err.Error() != expected {
+		t.Errorf("Expected %q but got %q", expected, err.Error())
+	}
+}
+
+type fakeFile struct {
+	doer Doer
+}
+
+func (f fakeFile) Fd() uintptr { return ^uintptr(0) }
+func (f fakeFile) Doer() Doer  { return f.doer }
+
+func TestRetry(t *testing.T) {
+	var calls int
+	interrupted := DoerFunc(func(fd, cmd, ptr uintptr) error {
+		calls++
+		switch {
+		case calls < 3:
+			return syscall.EINTR
+		case calls < 5:
+			return syscall.EAGAIN
+		}
+		return nil
+	})
+
+	for _, test := range []struct {
+		policy   Retry
+		expected error
+		calls    int
+	}{
+		{Retry{}, nil, 5},
+		{Retry{Max: 4}, nil, 5},
+		{Retry{Max: 3}, syscall.EAGAIN, 4},
+		{Retry{Max: 1}, syscall.EINTR, 2},
+	} {
+		calls = 0
+		err := test.policy.Do(interrupted, 0, 0, 0)
+		if err != test.expected || calls != test.calls {
+			t.Errorf("%+v: expected %v after %d calls but got %v after %d",
+				test.policy, test.expected, test.calls, err, calls)
+		}
+	}
+
+	calls = 0
+	err := Call(fakeFile{DoerFunc(func(fd, cmd, ptr uintptr) error {
+		calls++
+		return syscall.EBADF
+	})}, 0, 0)
+	if !errors.Is(err, syscall.EBADF) || calls != 1 {
+		t.Errorf("Unexpected %v after %d calls", err, calls)
+	}
+
+	defer SetDefaultRetry(SetDefaultRetry(Retry{Max: 3}))
+	calls = 0
+	err = Call(fakeFile{interrupted}, 0, 0)
+	if !errors.Is(err, syscall.EAGAIN) || calls != 4 {
+		t.Errorf("Expected EAGAIN after 4 calls but got %v after %d",
+			err, calls)
+	}
+	if policy := DefaultRetry(); policy.Max != 3 {
+		t.Errorf("Unexpected default policy %+v", policy)
+	}
+}
@@ -0,0 +1,60 @@
+package ioctl
+
+import "fmt"
+
+// layout is the ioctl code encoding of an architecture. The function
+// number and the driver character always take the 16 lower bits, the
+// size takes the next sizeBits bits and the direction the remaining
+// upper bits.
+//
+// See include/uapi/asm-generic/ioctl.h and the overrides in
+// arch/*/include/uapi/asm/ioctl.h of the Linux sources.
+type layout struct {
+	sizeBits          uint
+	none, read, write uint8
+}
+
+var (
+	genericLayout = layout{sizeBits: 14, none: 0, write: 1, read: 2}
+
+	// powerpc and mips use 3 bits for the direction and 13 bits for
+	// the size.
+	powerpcLayout = layout{sizeBits: 13, none: 1, read: 2, write: 4}
+	mipsLayout    = layout{sizeBits: 13, none: 1, read: 2, write: 4}
+)
+
+func (l layout) sizeShift() uint { return 16 }
+func (l layout) dirShift() uint  { return 16 + l.sizeBits }
+
+func (l layout) validDir(dir uint8) bool {
+	switch dir {
+	case l.none, l.read, l.write, l.read | l.write:
+		return true
+	}
+	return false
+}
+
+func (l layout) encode(dir uint8, sz uint16, uniq, fn uint8) uint32 {
+	if !l.validDir(dir) {
+		panic(fmt.Errorf("invalid ioctl code value: %d\n", dir))
+	}
+	if uint32(sz) >= 1<<l.sizeBits {
+		panic(fmt.Errorf("invalid ioctl size value: %d\n", sz))
+	}
+
+	var code uint32
+	code = code | (uint32(dir) << l.dirShift())
+	code = code | (uint32(sz) << l.sizeShift())
+	code = code | (uint32(uniq) << 8)
+	code = code | uint32(fn)
+	return code
+}
+
+func (l layout) decode(code uint32) Code {
+	return Code{
+		typ:  uint8(code >> l.dirShift()),
+		sz:   uint16((code >> l.sizeShift()) & (1<<l.sizeBits - 1)),
+		uniq: uint8(code >> 8),
+		fn:   uint8(code),
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !ppc && !ppc64 && !ppc64le && !mips && !mipsle && !mips64 && !mips64le
+
+package ioctl
+
+// include/uapi/asm-generic/ioctl.h
+
+const (
+	None  = uint8(0x0)
+	Write = uint8(0x1)
+	Read  = uint8(0x2)
+)
+
+var native = genericLayout
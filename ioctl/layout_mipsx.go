@@ -0,0 +1,13 @@
+//go:build mips || mipsle || mips64 || mips64le
+
+package ioctl
+
+// arch/mips/include/uapi/asm/ioctl.h
+
+const (
+	None  = uint8(0x1)
+	Read  = uint8(0x2)
+	Write = uint8(0x4)
+)
+
+var native = mipsLayout
@@ -0,0 +1,13 @@
+//go:build ppc || ppc64 || ppc64le
+
+package ioctl
+
+// arch/powerpc/include/uapi/asm/ioctl.h
+
+const (
+	None  = uint8(0x1)
+	Read  = uint8(0x2)
+	Write = uint8(0x4)
+)
+
+var native = powerpcLayout
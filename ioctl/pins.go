@@ -0,0 +1,36 @@
+package ioctl
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// Pins keeps the Go memory referenced by a request alive, and in
+// place, until the request returns. The garbage collector does not see
+// the addresses stored as integers inside a request argument, so the
+// buffers they point to, and the argument itself, must be pinned:
+//
+//	var pins ioctl.Pins
+//	defer pins.Unpin()
+//	res.fbIdPtr = pins.Addr(unsafe.Pointer(&fbids[0]))
+//	err := ioctl.Call(file, cmd, pins.Ptr(unsafe.Pointer(res)))
+type Pins struct {
+	pinner runtime.Pinner
+}
+
+// Addr pins the buffer at ptr and returns its address, as stored in
+// the request arguments.
+func (p *Pins) Addr(ptr unsafe.Pointer) uint64 {
+	return uint64(p.Ptr(ptr))
+}
+
+// Ptr pins the object at ptr and returns its address.
+func (p *Pins) Ptr(ptr unsafe.Pointer) uintptr {
+	p.pinner.Pin(ptr)
+	return uintptr(ptr)
+}
+
+// Unpin releases all the objects pinned.
+func (p *Pins) Unpin() {
+	p.pinner.Unpin()
+}
@@ -0,0 +1,66 @@
+package drm
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+type (
+	auth struct {
+		magic uint32
+	}
+
+	// Magic is the token a client gets from GetMagic and hands to the
+	// DRM master, which authenticates the client with AuthMagic.
+	Magic uint32
+)
+
+// SetMaster makes file the DRM master, the only client allowed to
+// change the display configuration. It fails with ErrBusy if another
+// client is the master.
+func SetMaster(file ioctl.File) error {
+	return ioctl.Call(file, uintptr(IOCTLSetMaster), 0)
+}
+
+// DropMaster releases the DRM master role, eg.: before switching to
+// another virtual terminal.
+func DropMaster(file ioctl.File) error {
+	return ioctl.Call(file, uintptr(IOCTLDropMaster), 0)
+}
+
+// IsMaster tells if file is the DRM master. Like libdrm, it tries to
+// authenticate the magic 0, which only the master is allowed to do and
+// which always fails otherwise.
+func IsMaster(file ioctl.File) bool {
+	err := AuthMagic(file, 0)
+	return !errors.Is(err, syscall.EACCES)
+}
+
+// GetMagic returns the magic of file, a client not authenticated yet.
+// The client sends it to the DRM master (eg.: the compositor) through
+// some other channel, then the master calls AuthMagic.
+func GetMagic(file ioctl.File) (Magic, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	a := &auth{}
+	err := ioctl.Call(file, uintptr(IOCTLGetMagic),
+		pins.Ptr(unsafe.Pointer(a)))
+	if err != nil {
+		return 0, err
+	}
+	return Magic(a.magic), nil
+}
+
+// AuthMagic authenticates the client owning magic. Only the DRM master
+// can authenticate clients, others get ErrNotMaster.
+func AuthMagic(masterFile ioctl.File, magic Magic) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	return ioctl.Call(masterFile, uintptr(IOCTLAuthMagic),
+		pins.Ptr(unsafe.Pointer(&auth{uint32(magic)})))
+}
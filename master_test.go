@@ -0,0 +1,102 @@
+package drm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+)
+
+func TestMaster(t *testing.T) {
+	card := drmtest.New()
+	conn := card.AddHead(drmtest.Mode(640, 480, 60))
+	crtcID := card.Crtcs[0].ID
+
+	var devs [2]*drm.Device
+	for i := range devs {
+		dev, err := card.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dev.Close()
+		devs[i] = dev
+	}
+	first, second := devs[0], devs[1]
+
+	fb, err := second.CreateFB(640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := second.AddFB(640, 480, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCrtc := func(dev *drm.Device) error {
+		return dev.SetCrtc(crtcID, fbID, 0, 0, &conn.ID, 1, &conn.Modes[0])
+	}
+
+	if !first.IsMaster() || second.IsMaster() {
+		t.Fatalf("The first device opened should be the only master")
+	}
+	if err := setCrtc(second); !errors.Is(err, drm.ErrNotMaster) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotMaster, err)
+	}
+	if err := second.SetMaster(); !errors.Is(err, drm.ErrBusy) {
+		t.Errorf("Expected %v but got %v", drm.ErrBusy, err)
+	}
+
+	// VT switch
+	if err := first.DropMaster(); err != nil {
+		t.Fatal(err)
+	}
+	if first.IsMaster() {
+		t.Errorf("Master not dropped")
+	}
+	if err := first.DropMaster(); !errors.Is(err, drm.ErrNotMaster) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotMaster, err)
+	}
+	if err := second.SetMaster(); err != nil {
+		t.Fatal(err)
+	}
+	if !second.IsMaster() {
+		t.Errorf("Master not acquired")
+	}
+	if err := setCrtc(second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthMagic(t *testing.T) {
+	card := drmtest.New()
+	var devs [3]*drm.Device
+	for i := range devs {
+		dev, err := card.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dev.Close()
+		devs[i] = dev
+	}
+	master, helper, other := devs[0], devs[1], devs[2]
+
+	magic, err := helper.GetMagic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Authenticated(helper.Fd()) {
+		t.Fatalf("Helper authenticated before AuthMagic")
+	}
+	if err := other.AuthMagic(magic); !errors.Is(err, drm.ErrNotMaster) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotMaster, err)
+	}
+	if err := master.AuthMagic(magic); err != nil {
+		t.Fatal(err)
+	}
+	if !card.Authenticated(helper.Fd()) || card.Authenticated(other.Fd()) {
+		t.Errorf("Wrong client authenticated")
+	}
+	if err := master.AuthMagic(magic); !errors.Is(err, drm.ErrBadMagic) {
+		t.Errorf("Expected %v but got %v", drm.ErrBadMagic, err)
+	}
+}
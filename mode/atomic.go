@@ -0,0 +1,329 @@
+package mode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+// Flags of Commit and PageFlip
+const (
+	PageFlipEvent          = 0x01
+	PageFlipAsync          = 0x02
+	PageFlipTargetAbsolute = 0x04
+	PageFlipTargetRelative = 0x08
+
+	AtomicTestOnly     = 0x0100
+	AtomicNonblock     = 0x0200
+	AtomicAllowModeset = 0x0400
+)
+
+type (
+	sysAtomic struct {
+		flags         uint32
+		countObjs     uint32
+		objsPtr       uint64
+		countPropsPtr uint64
+		propsPtr      uint64
+		propValuesPtr uint64
+		reserved      uint64
+		userData      uint64
+	}
+
+	atomicObject struct {
+		id     uint32
+		props  []uint32
+		values []uint64
+	}
+
+	// AtomicRequest is a set of property changes applied all at once
+	// by Commit. Properties are added by name, resolved through a
+	// cache kept across Reset, so a request can be reused frame after
+	// frame. The client capability drm.ClientCapAtomic must be enabled,
+	// otherwise the kernel hides the atomic properties.
+	AtomicRequest struct {
+		// UserData is passed back in the events of the commit.
+		UserData uint64
+
+		file ioctl.File
+		objs []atomicObject
+
+		props   map[uint32]*Property         // by id
+		objects map[uint32]map[string]uint32 // property ids by object
+	}
+
+	// AtomicError is the error of a commit rejected by the kernel. It
+	// names the object (and property) at fault when it can be found.
+	AtomicError struct {
+		ObjID   uint32 // zero if the failing object is unknown
+		ObjType uint32 // zero if unknown
+		Prop    string // empty if the property is unknown
+		Value   uint64
+
+		Objs []uint32 // objects of the commit
+		Err  error
+	}
+)
+
+var (
+	// DRM_IOWR(0xBC, struct drm_mode_atomic)
+	IOCTLModeAtomic = ioctl.Register("DRM_IOCTL_MODE_ATOMIC",
+		ioctl.IOWR(ioctlBase, 0xBC, sysAtomic{}))
+)
+
+func NewAtomicRequest(file ioctl.File) *AtomicRequest {
+	return &AtomicRequest{
+		file:    file,
+		props:   make(map[uint32]*Property),
+		objects: make(map[uint32]map[string]uint32),
+	}
+}
+
+// Property returns the property called name of the object objID.
+func (r *AtomicRequest) Property(objID uint32, name string) (*Property, error) {
+	byName, ok := r.objects[objID]
+	if !ok {
+		obj, err := GetObjectProperties(r.file, objID, ObjectAny)
+		if err != nil {
+			return nil, err
+		}
+		byName = make(map[string]uint32, len(obj.Props))
+		for _, id := range obj.Props {
+			prop, ok := r.props[id]
+			if !ok {
+				prop, err = GetProperty(r.file, id)
+				if err != nil {
+					return nil, err
+				}
+				r.props[id] = prop
+			}
+			byName[prop.Name] = id
+		}
+		r.objects[objID] = byName
+	}
+	id, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: object %d has no property %q",
+			ErrNoProperty, objID, name)
+	}
+	return r.props[id], nil
+}
+
+// AddProperty sets the property called name of the object objID to
+// value, replacing the value added before, if any.
+func (r *AtomicRequest) AddProperty(objID uint32, name string, value uint64) error {
+	prop, err := r.Property(objID, name)
+	if err != nil {
+		return err
+	}
+	r.AddPropertyID(objID, prop.ID, value)
+	return nil
+}
+
+// AddPropertyID is like AddProperty, with the property given by id.
+func (r *AtomicRequest) AddPropertyID(objID, propID uint32, value uint64) {
+	for i := range r.objs {
+		obj := &r.objs[i]
+		if obj.id != objID {
+			continue
+		}
+		for j, id := range obj.props {
+			if id == propID {
+				obj.values[j] = value
+				return
+			}
+		}
+		obj.props = append(obj.props, propID)
+		obj.values = append(obj.values, value)
+		return
+	}
+	r.objs = append(r.objs, atomicObject{
+		id:     objID,
+		props:  []uint32{propID},
+		values: []uint64{value},
+	})
+}
+
+// Reset removes the properties added, keeping the property cache.
+func (r *AtomicRequest) Reset() {
+	r.objs = nil
+	r.UserData = 0
+}
+
+// Commit applies the request, or only checks it if flags has
+// AtomicTestOnly. Changes needing a full modeset (eg.: a new mode) are
+// rejected unless flags has AtomicAllowModeset. Errors of the kernel
+// are returned as *AtomicError.
+func (r *AtomicRequest) Commit(flags uint32) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	var (
+		objs       = make([]uint32, len(r.objs))
+		countProps = make([]uint32, len(r.objs))
+		props      []uint32
+		values     []uint64
+	)
+	for i, obj := range r.objs {
+		objs[i] = obj.id
+		countProps[i] = uint32(len(obj.props))
+		props = append(props, obj.props...)
+		values = append(values, obj.values...)
+	}
+
+	req := &sysAtomic{
+		flags:     flags,
+		countObjs: uint32(len(objs)),
+		userData:  r.UserData,
+	}
+	if len(objs) > 0 {
+		req.objsPtr = pins.Addr(unsafe.Pointer(&objs[0]))
+		req.countPropsPtr = pins.Addr(unsafe.Pointer(&countProps[0]))
+	}
+	if len(props) > 0 {
+		req.propsPtr = pins.Addr(unsafe.Pointer(&props[0]))
+		req.propValuesPtr = pins.Addr(unsafe.Pointer(&values[0]))
+	}
+	err := ioctl.Call(r.file, uintptr(IOCTLModeAtomic),
+		pins.Ptr(unsafe.Pointer(req)))
+	if err != nil {
+		return r.diagnose(objs, err)
+	}
+	return nil
+}
+
+// diagnose looks for the object and property at fault in a commit the
+// kernel rejected. The kernel doesn't tell it, so the request is
+// checked against what is known of the objects and properties.
+func (r *AtomicRequest) diagnose(objs []uint32, err error) error {
+	aerr := &AtomicError{
+		Objs: objs,
+		Err:  err,
+	}
+	if !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOENT) &&
+		!errors.Is(err, syscall.ERANGE) && !errors.Is(err, syscall.ENOSPC) {
+		return aerr
+	}
+
+	for _, obj := range r.objs {
+		objType := r.objectType(obj.id)
+		if objType == 0 {
+			aerr.ObjID = obj.id
+			return aerr
+		}
+		for i, id := range obj.props {
+			prop, ok := r.props[id]
+			if !ok {
+				aerr.ObjID, aerr.ObjType = obj.id, objType
+				aerr.Prop = fmt.Sprintf("#%d", id)
+				aerr.Value = obj.values[i]
+				return aerr
+			}
+			if !r.validValue(prop, obj.values[i]) {
+				aerr.ObjID, aerr.ObjType = obj.id, objType
+				aerr.Prop = prop.Name
+				aerr.Value = obj.values[i]
+				return aerr
+			}
+		}
+	}
+	if len(r.objs) == 1 {
+		aerr.ObjID = r.objs[0].id
+		aerr.ObjType = r.objectType(aerr.ObjID)
+	}
+	return aerr
+}
+
+// objectType returns the type of the object id, or zero if there is no
+// such object.
+func (r *AtomicRequest) objectType(id uint32) uint32 {
+	for _, typ := range []uint32{ObjectCrtc, ObjectConnector, ObjectPlane} {
+		if _, err := GetObjectProperties(r.file, id, typ); err == nil {
+			return typ
+		}
+	}
+	return 0
+}
+
+// validValue tells if value is acceptable for prop, like the kernel
+// checks it before looking at the whole configuration.
+func (r *AtomicRequest) validValue(prop *Property, value uint64) bool {
+	switch {
+	case prop.IsImmutable():
+		return false
+	case prop.IsRange():
+		min, max := prop.Range()
+		return value >= min && value <= max
+	case prop.IsSignedRange():
+		min, max := prop.SignedRange()
+		return int64(value) >= min && int64(value) <= max
+	case prop.IsEnum():
+		_, ok := prop.EnumName(value)
+		return ok
+	case prop.IsBitmask():
+		var valid uint64
+		for _, enum := range prop.Enums {
+			valid |= 1 << enum.Value
+		}
+		return value&^valid == 0
+	case prop.IsBlob():
+		if value == 0 {
+			return true
+		}
+		_, err := GetBlob(r.file, uint32(value))
+		return err == nil
+	case prop.IsObject():
+		if value == 0 || prop.ObjectType() == ObjectFB {
+			// framebuffers have no properties to look them up
+			return true
+		}
+		_, err := GetObjectProperties(r.file, uint32(value),
+			prop.ObjectType())
+		return err == nil
+	}
+	return true
+}
+
+func (e *AtomicError) Error() string {
+	var obj string
+	switch {
+	case e.ObjID != 0:
+		obj = objectName(e.ObjType) + " " + fmt.Sprint(e.ObjID)
+		if e.Prop != "" {
+			obj += fmt.Sprintf(" %s=%d", e.Prop, e.Value)
+		}
+	case len(e.Objs) > 0:
+		ids := make([]string, len(e.Objs))
+		for i, id := range e.Objs {
+			ids[i] = fmt.Sprint(id)
+		}
+		obj = "objects " + strings.Join(ids, ", ")
+	default:
+		return fmt.Sprintf("atomic commit: %v", e.Err)
+	}
+	return fmt.Sprintf("atomic commit: %s: %v", obj, e.Err)
+}
+
+func (e *AtomicError) Unwrap() error { return e.Err }
+
+func objectName(objType uint32) string {
+	switch objType {
+	case ObjectCrtc:
+		return "CRTC"
+	case ObjectConnector:
+		return "connector"
+	case ObjectEncoder:
+		return "encoder"
+	case ObjectPlane:
+		return "plane"
+	case ObjectFB:
+		return "framebuffer"
+	case ObjectBlob:
+		return "blob"
+	}
+	return "object"
+}
@@ -0,0 +1,243 @@
+package mode
+
+import (
+	"fmt"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+// AtomicModeset is the atomic counterpart of SimpleModeset: it finds
+// the same connector and CRTC pairs, plus the primary plane of each
+// CRTC, then sets all of them in a single atomic commit. The state
+// found is restored by Close.
+type AtomicModeset struct {
+	SimpleModeset
+
+	req   *AtomicRequest
+	saved *AtomicRequest // commit restoring the original state
+	modes []savedMode    // modes of the original state
+	blobs []uint32       // mode blobs of the last Apply
+}
+
+// savedMode is the mode of a CRTC in the original state. The blob
+// holding it belongs to the kernel, and goes away once Apply replaces
+// it, so the mode is saved rather than the blob id.
+type savedMode struct {
+	crtc, prop uint32
+	info       *Info
+}
+
+// properties changed by Apply, saved to be restored on Close
+var (
+	atomicCrtcProps  = []string{"MODE_ID", "ACTIVE"}
+	atomicConnProps  = []string{"CRTC_ID"}
+	atomicPlaneProps = []string{"FB_ID", "CRTC_ID", "SRC_X", "SRC_Y",
+		"SRC_W", "SRC_H", "CRTC_X", "CRTC_Y", "CRTC_W", "CRTC_H"}
+)
+
+// NewAtomicModeset finds the connected monitors, like NewSimpleModeset,
+// and saves the state of their CRTCs and planes. The client capability
+// drm.ClientCapAtomic must be enabled on file.
+func NewAtomicModeset(file ioctl.File) (*AtomicModeset, error) {
+	mset := &AtomicModeset{
+		SimpleModeset: SimpleModeset{
+			driFile: file,
+		},
+		req:   NewAtomicRequest(file),
+		saved: NewAtomicRequest(file),
+	}
+	if err := mset.prepare(); err != nil {
+		return nil, err
+	}
+	if err := mset.findPlanes(); err != nil {
+		return nil, err
+	}
+	for _, dev := range mset.Modesets {
+		if err := mset.save(dev.Crtc, atomicCrtcProps); err != nil {
+			return nil, err
+		}
+		if err := mset.save(dev.Conn, atomicConnProps); err != nil {
+			return nil, err
+		}
+		if err := mset.save(dev.Plane, atomicPlaneProps); err != nil {
+			return nil, err
+		}
+	}
+	return mset, nil
+}
+
+// findPlanes selects a primary plane for the CRTC of each modeset,
+// preferring the plane already scanning out from it.
+func (mset *AtomicModeset) findPlanes() error {
+	res, err := GetResources(mset.driFile)
+	if err != nil {
+		return fmt.Errorf("Cannot retrieve resources: %w", err)
+	}
+	planeRes, err := GetPlaneResources(mset.driFile)
+	if err != nil {
+		return fmt.Errorf("Cannot retrieve planes: %w", err)
+	}
+
+	var primaries []*Plane
+	for _, id := range planeRes.Planes {
+		typ, err := GetPlaneType(mset.driFile, id)
+		if err != nil {
+			return fmt.Errorf("Cannot retrieve plane type: %w", err)
+		}
+		if typ != PlanePrimary {
+			continue
+		}
+		plane, err := GetPlane(mset.driFile, id)
+		if err != nil {
+			return fmt.Errorf("Cannot retrieve plane: %w", err)
+		}
+		primaries = append(primaries, plane)
+	}
+
+	used := make(map[uint32]bool)
+	for i := range mset.Modesets {
+		dev := &mset.Modesets[i]
+		var index uint
+		for j, id := range res.Crtcs {
+			if id == dev.Crtc {
+				index = uint(j)
+			}
+		}
+		for _, plane := range primaries {
+			if used[plane.ID] || plane.PossibleCrtcs&(1<<index) == 0 {
+				continue
+			}
+			if dev.Plane == 0 || plane.CrtcID == dev.Crtc {
+				dev.Plane = plane.ID
+			}
+		}
+		if dev.Plane == 0 {
+			return fmt.Errorf("Cannot find a primary plane for CRTC %d",
+				dev.Crtc)
+		}
+		used[dev.Plane] = true
+	}
+	return nil
+}
+
+// save adds the current values of the properties names of the object
+// objID to the commit restoring the original state.
+func (mset *AtomicModeset) save(objID uint32, names []string) error {
+	set, err := GetPropertySet(mset.driFile, objID, ObjectAny)
+	if err != nil {
+		return fmt.Errorf("Cannot retrieve properties of object %d: %w",
+			objID, err)
+	}
+	for _, name := range names {
+		value, ok := set.Value(name)
+		if !ok {
+			return fmt.Errorf("%w: object %d has no property %q",
+				ErrNoProperty, objID, name)
+		}
+		mset.saved.AddPropertyID(objID, set.Props[name].ID, value)
+		if name == "MODE_ID" && value != 0 {
+			info, err := GetModeBlob(mset.driFile, uint32(value))
+			if err != nil {
+				return fmt.Errorf("Cannot retrieve mode of CRTC %d: %w",
+					objID, err)
+			}
+			mset.modes = append(mset.modes,
+				savedMode{objID, set.Props[name].ID, info})
+		}
+	}
+	return nil
+}
+
+// Apply shows the framebuffer fbs[i], of the size of the mode, on the
+// ith modeset. All the heads are set in a single commit, tested before.
+// The mode blobs of the previous Apply are destroyed once replaced.
+func (mset *AtomicModeset) Apply(fbs []uint32) error {
+	if len(fbs) != len(mset.Modesets) {
+		return fmt.Errorf("Expected %d framebuffers but got %d",
+			len(mset.Modesets), len(fbs))
+	}
+
+	var blobs []uint32
+	err := mset.apply(fbs, &blobs)
+	if err == nil {
+		// the CRTCs hold the new blobs, the previous ones are unused
+		blobs, mset.blobs = mset.blobs, blobs
+	}
+	destroyBlobs(mset.driFile, blobs)
+	return err
+}
+
+func (mset *AtomicModeset) apply(fbs []uint32, blobs *[]uint32) error {
+	mset.req.Reset()
+	for i, dev := range mset.Modesets {
+		blob, err := CreateModeBlob(mset.driFile, &dev.Mode)
+		if err != nil {
+			return fmt.Errorf("Cannot create mode blob: %w", err)
+		}
+		*blobs = append(*blobs, blob)
+
+		for _, prop := range []struct {
+			obj   uint32
+			name  string
+			value uint64
+		}{
+			{dev.Crtc, "MODE_ID", uint64(blob)},
+			{dev.Crtc, "ACTIVE", 1},
+			{dev.Conn, "CRTC_ID", uint64(dev.Crtc)},
+			{dev.Plane, "FB_ID", uint64(fbs[i])},
+			{dev.Plane, "CRTC_ID", uint64(dev.Crtc)},
+			{dev.Plane, "SRC_X", 0},
+			{dev.Plane, "SRC_Y", 0},
+			{dev.Plane, "SRC_W", uint64(Fixed16Int(int(dev.Width)))},
+			{dev.Plane, "SRC_H", uint64(Fixed16Int(int(dev.Height)))},
+			{dev.Plane, "CRTC_X", 0},
+			{dev.Plane, "CRTC_Y", 0},
+			{dev.Plane, "CRTC_W", uint64(dev.Width)},
+			{dev.Plane, "CRTC_H", uint64(dev.Height)},
+		} {
+			err := mset.req.AddProperty(prop.obj, prop.name, prop.value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := mset.req.Commit(AtomicTestOnly | AtomicAllowModeset); err != nil {
+		return err
+	}
+	return mset.req.Commit(AtomicAllowModeset)
+}
+
+// Close restores the state saved by NewAtomicModeset, with new blobs
+// holding the original modes, and destroys the mode blobs created.
+func (mset *AtomicModeset) Close() error {
+	var (
+		blobs []uint32
+		err   error
+	)
+	for _, saved := range mset.modes {
+		var blob uint32
+		blob, err = CreateModeBlob(mset.driFile, saved.info)
+		if err != nil {
+			err = fmt.Errorf("Cannot create mode blob: %w", err)
+			break
+		}
+		blobs = append(blobs, blob)
+		mset.saved.AddPropertyID(saved.crtc, saved.prop, uint64(blob))
+	}
+	if err == nil {
+		err = mset.saved.Commit(AtomicAllowModeset)
+	}
+	// the CRTCs keep the blobs they use alive
+	destroyBlobs(mset.driFile, append(blobs, mset.blobs...))
+	mset.blobs = nil
+	return err
+}
+
+// destroyBlobs destroys the blobs, ignoring the errors: the kernel
+// frees the blobs of a client when it closes the device anyway.
+func destroyBlobs(file ioctl.File, blobs []uint32) {
+	for _, blob := range blobs {
+		DestroyBlob(file, blob)
+	}
+}
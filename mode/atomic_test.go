@@ -0,0 +1,287 @@
+package mode_test
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// atomicCard returns a card with a head and its primary plane, opened
+// by an atomic client, and a framebuffer of the head size.
+func atomicCard(t *testing.T) (*drmtest.Card, *drm.Device, *drmtest.Connector, *drmtest.Plane, uint32) {
+	card := drmtest.New()
+	conn := card.AddHead(drmtest.Mode(1280, 720, 60))
+	primary := card.AddPlane(mode.PlanePrimary, 1, fourcc.XRGB8888)
+	dev := openFake(t, card)
+	if err := dev.SetClientCap(drm.ClientCapAtomic, 1); err != nil {
+		t.Fatal(err)
+	}
+	fb, err := dev.CreateFB(1280, 720, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := dev.AddFB(1280, 720, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return card, dev, conn, primary, fbID
+}
+
+func addProps(t *testing.T, req *mode.AtomicRequest, objID uint32, props map[string]uint64) {
+	for name, value := range props {
+		if err := req.AddProperty(objID, name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAtomicCommit(t *testing.T) {
+	card, dev, conn, primary, fbID := atomicCard(t)
+	crtc := card.Crtcs[0]
+
+	blob, err := mode.CreateModeBlob(dev, &conn.Modes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := mode.NewAtomicRequest(dev)
+	addProps(t, req, crtc.ID, map[string]uint64{
+		"MODE_ID": uint64(blob),
+		"ACTIVE":  1,
+	})
+	addProps(t, req, conn.ID, map[string]uint64{"CRTC_ID": uint64(crtc.ID)})
+	addProps(t, req, primary.ID, map[string]uint64{
+		"FB_ID":   uint64(fbID),
+		"CRTC_ID": uint64(crtc.ID),
+		"SRC_W":   uint64(mode.Fixed16Int(1280)),
+		"SRC_H":   uint64(mode.Fixed16Int(720)),
+		"CRTC_W":  1280,
+		"CRTC_H":  720,
+	})
+
+	err = req.Commit(mode.AtomicTestOnly)
+	if !errors.Is(err, drm.ErrInvalidMode) {
+		t.Errorf("Expected %v for a modeset not allowed but got %v",
+			drm.ErrInvalidMode, err)
+	}
+	var aerr *mode.AtomicError
+	if !errors.As(err, &aerr) || len(aerr.Objs) != 3 {
+		t.Errorf("Expected an AtomicError with 3 objects but got %#v", err)
+	}
+	if err := req.Commit(mode.AtomicTestOnly | mode.AtomicAllowModeset); err != nil {
+		t.Fatal(err)
+	}
+	if crtc.Active || primary.FbID != 0 {
+		t.Errorf("Test only commit changed the state")
+	}
+
+	if err := req.Commit(mode.AtomicAllowModeset); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mode.GetCrtc(dev, crtc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BufferID != fbID || got.ModeValid != 1 || got.Mode != conn.Modes[0] {
+		t.Errorf("Unexpected CRTC: %+v", got)
+	}
+	set, err := mode.GetPropertySet(dev, primary.ID, mode.ObjectPlane)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Values["FB_ID"] != uint64(fbID) || set.Values["CRTC_W"] != 1280 {
+		t.Errorf("Unexpected plane properties: %v", set.Values)
+	}
+
+	// page flip to the same framebuffer, no modeset needed
+	req.Reset()
+	addProps(t, req, primary.ID, map[string]uint64{"FB_ID": uint64(fbID)})
+	if err := req.Commit(mode.AtomicNonblock); err != nil {
+		t.Fatal(err)
+	}
+
+	other := openFake(t, card)
+	if err := other.SetClientCap(drm.ClientCapAtomic, 1); err != nil {
+		t.Fatal(err)
+	}
+	req = mode.NewAtomicRequest(other)
+	addProps(t, req, primary.ID, map[string]uint64{"FB_ID": 0, "CRTC_ID": 0})
+	if err := req.Commit(0); !errors.Is(err, drm.ErrNotMaster) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotMaster, err)
+	}
+}
+
+func TestAtomicErrors(t *testing.T) {
+	card, dev, _, primary, fbID := atomicCard(t)
+	crtc := card.Crtcs[0]
+
+	req := mode.NewAtomicRequest(dev)
+	if err := req.AddProperty(crtc.ID, "GAMMA_LUT", 0); !errors.Is(err, mode.ErrNoProperty) {
+		t.Errorf("Expected %v but got %v", mode.ErrNoProperty, err)
+	}
+
+	active, err := req.Property(crtc.ID, "ACTIVE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddPropertyID(crtc.ID, active.ID, 2)
+	err = req.Commit(mode.AtomicAllowModeset)
+	var aerr *mode.AtomicError
+	if !errors.As(err, &aerr) || aerr.ObjID != crtc.ID ||
+		aerr.ObjType != mode.ObjectCrtc || aerr.Prop != "ACTIVE" ||
+		aerr.Value != 2 {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if !strings.Contains(err.Error(), "CRTC 1 ACTIVE=2") {
+		t.Errorf("Undescriptive error: %v", err)
+	}
+
+	// a framebuffer without a CRTC: every value is valid, but the
+	// plane is the only object of the request
+	req.Reset()
+	addProps(t, req, primary.ID, map[string]uint64{"FB_ID": uint64(fbID)})
+	err = req.Commit(0)
+	if !errors.As(err, &aerr) || aerr.ObjID != primary.ID ||
+		aerr.ObjType != mode.ObjectPlane || aerr.Prop != "" ||
+		!errors.Is(err, drm.ErrInvalidMode) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Reset()
+	req.AddPropertyID(1000, active.ID, 1)
+	err = req.Commit(0)
+	if !errors.As(err, &aerr) || aerr.ObjID != 1000 || aerr.ObjType != 0 ||
+		!errors.Is(err, drm.ErrNotFound) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	// the atomic properties are hidden from legacy clients
+	legacy := openFake(t, card)
+	req = mode.NewAtomicRequest(legacy)
+	if err := req.AddProperty(crtc.ID, "ACTIVE", 1); !errors.Is(err, mode.ErrNoProperty) {
+		t.Errorf("Expected %v but got %v", mode.ErrNoProperty, err)
+	}
+}
+
+func TestAtomicModeset(t *testing.T) {
+	card := drmtest.New()
+	conn1 := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	card.AddHead() // disconnected
+	conn3 := card.AddHead(drmtest.Mode(1280, 1024, 60))
+	var primaries []*drmtest.Plane
+	for i := range card.Crtcs {
+		primaries = append(primaries,
+			card.AddPlane(mode.PlanePrimary, 1<<uint(i), fourcc.XRGB8888))
+	}
+	card.AddPlane(mode.PlaneCursor, 7, fourcc.ARGB8888)
+	dev := openFake(t, card)
+
+	newFB := func(width, height uint16) uint32 {
+		fb, err := dev.CreateFB(width, height, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := dev.AddFB(width, height, 24, 32, fb.Pitch, fb.Handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// the console is shown on the first head
+	console := newFB(1920, 1080)
+	err := dev.SetCrtc(card.Crtcs[0].ID, console, 0, 0, &conn1.ID, 1, &conn1.Modes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mset, err := dev.NewAtomicModeset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mset.Modesets) != 2 {
+		t.Fatalf("Expected 2 modesets but got %d", len(mset.Modesets))
+	}
+	for i, expected := range []struct {
+		conn  *drmtest.Connector
+		crtc  *drmtest.Crtc
+		plane *drmtest.Plane
+	}{
+		{conn1, card.Crtcs[0], primaries[0]},
+		{conn3, card.Crtcs[2], primaries[2]},
+	} {
+		m := mset.Modesets[i]
+		if m.Conn != expected.conn.ID || m.Crtc != expected.crtc.ID ||
+			m.Plane != expected.plane.ID {
+			t.Errorf("Unexpected modeset: %+v", m)
+		}
+	}
+
+	var commits int
+	card.BeforeRequest = func(code uint32) {
+		if code == mode.IOCTLModeAtomic {
+			commits++
+		}
+	}
+	fbs := []uint32{newFB(1920, 1080), newFB(1280, 1024)}
+	if err := mset.Apply(fbs); err != nil {
+		t.Fatal(err)
+	}
+	if commits != 2 {
+		t.Errorf("Expected a test and a real commit but got %d commits", commits)
+	}
+	for i, crtc := range []*drmtest.Crtc{card.Crtcs[0], card.Crtcs[2]} {
+		if !crtc.Active || crtc.FbID != fbs[i] ||
+			*crtc.Mode != mset.Modesets[i].Mode {
+			t.Errorf("Head %d not set: %+v", i, crtc)
+		}
+	}
+	if len(card.Blobs) < 2 {
+		t.Errorf("Expected the mode blobs but got %v", card.Blobs)
+	}
+	modeBlobs := func() int {
+		var n int
+		for _, blob := range card.Blobs {
+			if _, err := mode.InfoFromBytes(blob.Data); err == nil {
+				n++
+			}
+		}
+		return n
+	}
+	// the blob of the console mode is gone, Apply keeps a blob per head
+	if n := modeBlobs(); n != 2 {
+		t.Errorf("Expected 2 mode blobs but got %d", n)
+	}
+	if err := mset.Apply(fbs); err != nil {
+		t.Fatal(err)
+	}
+	card.Inject(mode.IOCTLModeAtomic, syscall.EINVAL)
+	if err := mset.Apply(fbs); err == nil {
+		t.Error("Expected the rejected commit to fail")
+	}
+	if n := modeBlobs(); n != 2 {
+		t.Errorf("Expected 2 mode blobs after Apply again but got %d", n)
+	}
+
+	if err := mset.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if crtc := card.Crtcs[0]; !crtc.Active || crtc.FbID != console ||
+		*crtc.Mode != conn1.Modes[0] {
+		t.Errorf("Console not restored: %+v", crtc)
+	}
+	if crtc := card.Crtcs[2]; crtc.Active || crtc.FbID != 0 || primaries[2].FbID != 0 {
+		t.Errorf("Second head not disabled: %+v", crtc)
+	}
+	for _, blob := range card.Blobs {
+		if _, err := mode.InfoFromBytes(blob.Data); err == nil &&
+			blob.ID != card.Crtcs[0].ModeBlob {
+			t.Errorf("Mode blob %d left behind", blob.ID)
+		}
+	}
+}
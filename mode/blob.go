@@ -0,0 +1,129 @@
+package mode
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+type (
+	sysGetBlob struct {
+		id     uint32
+		length uint32
+		data   uint64
+	}
+
+	sysCreateBlob struct {
+		data   uint64
+		length uint32
+		id     uint32
+	}
+
+	sysDestroyBlob struct {
+		id uint32
+	}
+)
+
+var (
+	// DRM_IOWR(0xAC, struct drm_mode_get_blob)
+	IOCTLModeGetPropBlob = ioctl.Register("DRM_IOCTL_MODE_GETPROPBLOB",
+		ioctl.IOWR(ioctlBase, 0xAC, sysGetBlob{}))
+
+	// DRM_IOWR(0xBD, struct drm_mode_create_blob)
+	IOCTLModeCreatePropBlob = ioctl.Register("DRM_IOCTL_MODE_CREATEPROPBLOB",
+		ioctl.IOWR(ioctlBase, 0xBD, sysCreateBlob{}))
+
+	// DRM_IOWR(0xBE, struct drm_mode_destroy_blob)
+	IOCTLModeDestroyPropBlob = ioctl.Register("DRM_IOCTL_MODE_DESTROYPROPBLOB",
+		ioctl.IOWR(ioctlBase, 0xBE, sysDestroyBlob{}))
+)
+
+// GetBlob returns the data of the property blob blobID (eg.: the value
+// of the "EDID" connector property).
+func GetBlob(file ioctl.File, blobID uint32) ([]byte, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	blob := &sysGetBlob{}
+	blob.id = blobID
+	err := ioctl.Call(file, uintptr(IOCTLModeGetPropBlob),
+		pins.Ptr(unsafe.Pointer(blob)))
+	if err != nil {
+		return nil, err
+	}
+	if blob.length == 0 {
+		return []byte{}, nil
+	}
+
+	// blobs are immutable, no need to retry
+	data := make([]byte, blob.length)
+	blob.data = pins.Addr(unsafe.Pointer(&data[0]))
+	err = ioctl.Call(file, uintptr(IOCTLModeGetPropBlob),
+		pins.Ptr(unsafe.Pointer(blob)))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateBlob creates a property blob with a copy of data, that lives
+// until DestroyBlob is called or file is closed.
+func CreateBlob(file ioctl.File, data []byte) (uint32, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	blob := &sysCreateBlob{}
+	blob.length = uint32(len(data))
+	if len(data) > 0 {
+		blob.data = pins.Addr(unsafe.Pointer(&data[0]))
+	}
+	err := ioctl.Call(file, uintptr(IOCTLModeCreatePropBlob),
+		pins.Ptr(unsafe.Pointer(blob)))
+	if err != nil {
+		return 0, err
+	}
+	return blob.id, nil
+}
+
+func DestroyBlob(file ioctl.File, blobID uint32) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	return ioctl.Call(file, uintptr(IOCTLModeDestroyPropBlob),
+		pins.Ptr(unsafe.Pointer(&sysDestroyBlob{blobID})))
+}
+
+// CreateModeBlob creates a blob holding info, to be set as the
+// "MODE_ID" property of a CRTC.
+func CreateModeBlob(file ioctl.File, info *Info) (uint32, error) {
+	return CreateBlob(file, info.Bytes())
+}
+
+// GetModeBlob returns the mode held by the blob blobID (eg.: the value
+// of the "MODE_ID" property of a CRTC).
+func GetModeBlob(file ioctl.File, blobID uint32) (*Info, error) {
+	data, err := GetBlob(file, blobID)
+	if err != nil {
+		return nil, err
+	}
+	return InfoFromBytes(data)
+}
+
+// Bytes returns info as the kernel lays out a struct drm_mode_modeinfo.
+func (info *Info) Bytes() []byte {
+	data := make([]byte, unsafe.Sizeof(*info))
+	copy(data, unsafe.Slice((*byte)(unsafe.Pointer(info)), len(data)))
+	return data
+}
+
+// InfoFromBytes decodes a mode laid out as a struct drm_mode_modeinfo.
+func InfoFromBytes(data []byte) (*Info, error) {
+	info := &Info{}
+	if len(data) != int(unsafe.Sizeof(*info)) {
+		return nil, fmt.Errorf("mode: invalid mode blob size %d, "+
+			"expected %d", len(data), unsafe.Sizeof(*info))
+	}
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(info)), len(data)), data)
+	return info, nil
+}
@@ -0,0 +1,76 @@
+package mode_test
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestBlobs(t *testing.T) {
+	card := drmtest.New()
+	edid := card.AddBlob([]byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00})
+	dev := openFake(t, card)
+	other := openFake(t, card)
+
+	data, err := mode.GetBlob(dev, edid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, edid.Data) {
+		t.Errorf("Unexpected EDID: %x", data)
+	}
+
+	id, err := mode.CreateBlob(dev, []byte("gamma"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := mode.GetBlob(other, id); err != nil || string(data) != "gamma" {
+		t.Errorf("Unexpected blob %q (%v)", data, err)
+	}
+	if err := mode.DestroyBlob(other, id); !errors.Is(err, syscall.EPERM) {
+		t.Errorf("Expected EPERM destroying a blob of another client but got %v", err)
+	}
+	if err := mode.DestroyBlob(dev, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mode.GetBlob(dev, id); !errors.Is(err, drm.ErrNotFound) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotFound, err)
+	}
+	if _, err := mode.CreateBlob(dev, nil); err == nil {
+		t.Errorf("Expected error creating an empty blob")
+	}
+}
+
+func TestModeBlob(t *testing.T) {
+	card := drmtest.New()
+	dev := openFake(t, card)
+
+	info := drmtest.Mode(2560, 1440, 144)
+	if n := len(info.Bytes()); n != 68 {
+		t.Fatalf("Expected the 68 bytes of drm_mode_modeinfo but got %d", n)
+	}
+	id, err := mode.CreateModeBlob(dev, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mode.GetModeBlob(dev, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != info {
+		t.Errorf("Expected %+v but got %+v", info, *got)
+	}
+
+	id, err = mode.CreateBlob(dev, []byte("not a mode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mode.GetModeBlob(dev, id); err == nil {
+		t.Errorf("Expected error decoding a blob that is not a mode")
+	}
+}
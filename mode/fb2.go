@@ -0,0 +1,109 @@
+package mode
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+// Flags of AddFB2
+const (
+	FBInterlaced = 0x01 // interlaced framebuffer
+	FBModifiers  = 0x02 // the modifiers are set, see drm.CapAddFB2Modifiers
+)
+
+type sysFBCmd2 struct {
+	fbID          uint32
+	width, height uint32
+	pixelFormat   uint32
+	flags         uint32
+
+	handles   [4]uint32
+	pitches   [4]uint32
+	offsets   [4]uint32
+	modifiers [4]uint64
+}
+
+var (
+	// DRM_IOWR(0xB8, struct drm_mode_fb_cmd2)
+	IOCTLModeAddFB2 = ioctl.Register("DRM_IOCTL_MODE_ADDFB2",
+		ioctl.IOWR(ioctlBase, 0xB8, sysFBCmd2{}))
+)
+
+// ErrInvalidFB is returned by AddFB2 for planes not matching the
+// layout of the format.
+var ErrInvalidFB = errors.New("mode: invalid framebuffer planes")
+
+// AddFB2 adds a framebuffer in the given format, whose planes are the
+// buffer objects handles (the same buffer object can hold many planes,
+// at different offsets). The entries past the planes of the format
+// must be zero. The modifiers of the planes, describing their tiling
+// or compression, are only used with the FBModifiers flag and must
+// then all be the same.
+func AddFB2(file ioctl.File, width, height uint16, format fourcc.Format,
+	handles, pitches, offsets [4]uint32, modifiers [4]fourcc.Modifier,
+	flags uint32) (uint32, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	if err := checkFBPlanes(width, format, handles, pitches, offsets,
+		modifiers, flags); err != nil {
+		return 0, err
+	}
+	f := &sysFBCmd2{
+		width:       uint32(width),
+		height:      uint32(height),
+		pixelFormat: uint32(format),
+		flags:       flags,
+		handles:     handles,
+		pitches:     pitches,
+		offsets:     offsets,
+	}
+	for i, modifier := range modifiers {
+		f.modifiers[i] = uint64(modifier)
+	}
+	err := ioctl.Call(file, uintptr(IOCTLModeAddFB2),
+		pins.Ptr(unsafe.Pointer(f)))
+	if err != nil {
+		return 0, err
+	}
+	return f.fbID, nil
+}
+
+// checkFBPlanes checks the planes of a framebuffer against the layout
+// of its format, leaving the formats unknown to the kernel.
+func checkFBPlanes(width uint16, format fourcc.Format,
+	handles, pitches, offsets [4]uint32, modifiers [4]fourcc.Modifier,
+	flags uint32) error {
+	info, known := format.Info()
+	for i := 0; i < len(handles); i++ {
+		switch {
+		case !known && handles[i] == 0:
+			continue
+		case known && i >= info.Planes:
+			if handles[i] != 0 || pitches[i] != 0 || offsets[i] != 0 ||
+				modifiers[i] != 0 {
+				return fmt.Errorf("%w: plane %d set, %s has %d",
+					ErrInvalidFB, i, format, info.Planes)
+			}
+		case handles[i] == 0 || pitches[i] == 0:
+			return fmt.Errorf("%w: plane %d without handle or pitch",
+				ErrInvalidFB, i)
+		case known && flags&FBModifiers == 0 &&
+			int(pitches[i]) < info.MinPitch(i, int(width)):
+			return fmt.Errorf("%w: plane %d pitch %d, %s needs %d",
+				ErrInvalidFB, i, pitches[i], format,
+				info.MinPitch(i, int(width)))
+		case flags&FBModifiers == 0 && modifiers[i] != 0:
+			return fmt.Errorf("%w: plane %d modifier without FBModifiers",
+				ErrInvalidFB, i)
+		case modifiers[i] != modifiers[0]:
+			return fmt.Errorf("%w: plane %d modifier %v, plane 0 %v",
+				ErrInvalidFB, i, modifiers[i], modifiers[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mode_test
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestAddFB2(t *testing.T) {
+	card := drmtest.New()
+	dev := openFake(t, card)
+
+	// NV12: luma plane then half-sized chroma plane, in one buffer
+	bo, err := dev.CreateFB(640, 720, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handles := [4]uint32{bo.Handle, bo.Handle}
+	pitches := [4]uint32{bo.Pitch, bo.Pitch}
+	offsets := [4]uint32{0, bo.Pitch * 480}
+	id, err := dev.AddFB2(640, 480, fourcc.NV12, handles, pitches, offsets,
+		[4]fourcc.Modifier{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb := card.Framebuffers[id]
+	if fb == nil || fb.Format != fourcc.NV12 || fb.Width != 640 ||
+		fb.Handles != handles || fb.Offsets != offsets || fb.Flags != 0 {
+		t.Errorf("Unexpected framebuffer: %+v", fb)
+	}
+
+	for _, test := range []struct {
+		name      string
+		format    fourcc.Format
+		handles   [4]uint32
+		modifiers [4]fourcc.Modifier
+		flags     uint32
+		expected  error
+	}{
+		{"missing plane", fourcc.NV12, [4]uint32{bo.Handle}, [4]fourcc.Modifier{}, 0, mode.ErrInvalidFB},
+		{"extra plane", fourcc.XRGB8888, handles, [4]fourcc.Modifier{}, 0, mode.ErrInvalidFB},
+		{"small pitch", fourcc.XRGB8888, [4]uint32{bo.Handle}, [4]fourcc.Modifier{}, 0, mode.ErrInvalidFB},
+		{"modifier without flag", fourcc.NV12, handles, [4]fourcc.Modifier{1, 1}, 0, mode.ErrInvalidFB},
+		{"different modifiers", fourcc.NV12, handles, [4]fourcc.Modifier{1, 2}, mode.FBModifiers, mode.ErrInvalidFB},
+		{"no modifiers cap", fourcc.NV12, handles, [4]fourcc.Modifier{}, mode.FBModifiers, syscall.EINVAL},
+		{"no buffer", fourcc.NV12, [4]uint32{1000, 1000}, [4]fourcc.Modifier{}, 0, drm.ErrNotFound},
+	} {
+		var pitches, offsets [4]uint32
+		for i, handle := range test.handles {
+			if handle != 0 {
+				pitches[i] = bo.Pitch
+			}
+		}
+		_, err := mode.AddFB2(dev, 640, 480, test.format, test.handles,
+			pitches, offsets, test.modifiers, test.flags)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, err)
+		}
+	}
+
+	card.Caps[drm.CapAddFB2Modifiers] = 1
+	modifiers := [4]fourcc.Modifier{fourcc.ModLinear, fourcc.ModLinear}
+	id, err = mode.AddFB2(dev, 640, 480, fourcc.NV12, handles, pitches, offsets,
+		modifiers, mode.FBModifiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fb := card.Framebuffers[id]; fb.Flags != mode.FBModifiers ||
+		fb.Modifier != fourcc.ModLinear {
+		t.Errorf("Unexpected framebuffer: %+v", fb)
+	}
+	if err := mode.RmFB(dev, id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+package mode
+
+import (
+	"fmt"
+	"image"
+)
+
+type (
+	// Fixed16 is an unsigned 16.16 fixed-point number, the unit of the
+	// plane source coordinates.
+	Fixed16 uint32
+
+	// FixedRect is a rectangle in 16.16 fixed-point coordinates, with
+	// its origin at X, Y and size W x H.
+	FixedRect struct {
+		X, Y, W, H Fixed16
+	}
+)
+
+// MaxFixed16 is the largest Fixed16, just below 65536.
+const MaxFixed16 Fixed16 = 1<<32 - 1
+
+// Fixed16Int returns i as a Fixed16. i must be in [0, 65535], the
+// values out of range are clamped to 0 or MaxFixed16.
+func Fixed16Int(i int) Fixed16 {
+	switch {
+	case i < 0:
+		return 0
+	case i > MaxFixed16.Int():
+		return MaxFixed16
+	}
+	return Fixed16(i << 16)
+}
+
+// Fixed16Float returns f as a Fixed16, rounded to the nearest 1/65536.
+// f must be in [0, 65536), the values out of range are clamped to 0 or
+// MaxFixed16, and NaN is 0.
+func Fixed16Float(f float64) Fixed16 {
+	f = f*65536 + 0.5
+	switch {
+	case !(f >= 1): // also NaN
+		return 0
+	case f >= float64(MaxFixed16):
+		return MaxFixed16
+	}
+	return Fixed16(f)
+}
+
+// Int returns the integer part of f.
+func (f Fixed16) Int() int {
+	return int(f >> 16)
+}
+
+// Float64 returns f as a float64.
+func (f Fixed16) Float64() float64 {
+	return float64(f) / 65536
+}
+
+// String returns f as a decimal number, eg.: 1.5
+func (f Fixed16) String() string {
+	return fmt.Sprintf("%g", f.Float64())
+}
+
+// FixedRectOf returns r in fixed-point coordinates. The coordinates
+// must be in [0, 65535], they are clamped like with Fixed16Int.
+func FixedRectOf(r image.Rectangle) FixedRect {
+	return FixedRect{
+		X: Fixed16Int(r.Min.X),
+		Y: Fixed16Int(r.Min.Y),
+		W: Fixed16Int(r.Dx()),
+		H: Fixed16Int(r.Dy()),
+	}
+}
+
+// Rect returns r as an image.Rectangle, truncating the fractional
+// parts.
+func (r FixedRect) Rect() image.Rectangle {
+	return image.Rect(r.X.Int(), r.Y.Int(), (r.X + r.W).Int(),
+		(r.Y + r.H).Int())
+}
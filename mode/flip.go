@@ -0,0 +1,50 @@
+package mode
+
+import (
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+type sysPageFlip struct {
+	crtcID   uint32
+	fbID     uint32
+	flags    uint32
+	sequence uint32 // target vblank of the PageFlipTarget flags
+	userData uint64
+}
+
+var (
+	// DRM_IOWR(0xB0, struct drm_mode_crtc_page_flip)
+	IOCTLModePageFlip = ioctl.Register("DRM_IOCTL_MODE_PAGE_FLIP",
+		ioctl.IOWR(ioctlBase, 0xB0, sysPageFlip{}))
+)
+
+// PageFlip schedules the CRTC crtcID to scan out from the framebuffer
+// fbID at the next vertical blank, without a full modeset. With the
+// PageFlipEvent flag, a flip complete event carrying userData is sent
+// when the flip is done, see drm.ReadEvents. PageFlipAsync flips
+// immediately, if the driver has drm.CapAsyncPageFlip. Only one flip
+// can be pending on a CRTC, the next ones fail with drm.ErrBusy.
+func PageFlip(file ioctl.File, crtcID, fbID, flags uint32, userData uint64) error {
+	return PageFlipTarget(file, crtcID, fbID, flags, 0, userData)
+}
+
+// PageFlipTarget is PageFlip at a given vblank of the CRTC instead of
+// the next one. With the PageFlipTargetAbsolute flag, target is the
+// vblank sequence to flip at; with PageFlipTargetRelative, it is
+// counted from the current vblank. It needs drm.CapPageFlipTarget.
+func PageFlipTarget(file ioctl.File, crtcID, fbID, flags, target uint32, userData uint64) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	req := &sysPageFlip{
+		crtcID:   crtcID,
+		fbID:     fbID,
+		flags:    flags,
+		sequence: target,
+		userData: userData,
+	}
+	return ioctl.Call(file, uintptr(IOCTLModePageFlip),
+		pins.Ptr(unsafe.Pointer(req)))
+}
@@ -0,0 +1,107 @@
+package mode_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestPageFlip(t *testing.T) {
+	card, dev, conn, _, fbID := atomicCard(t)
+	crtc := card.Crtcs[0]
+	info := conn.Modes[0]
+	if err := dev.SetCrtc(crtc.ID, fbID, 0, 0, &conn.ID, 1, &info); err != nil {
+		t.Fatal(err)
+	}
+	back, err := dev.CreateFB(1280, 720, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backID, err := dev.AddFB(1280, 720, 24, 32, back.Pitch, back.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mode.PageFlip(dev, crtc.ID, backID, mode.PageFlipEvent, 7); err != nil {
+		t.Fatal(err)
+	}
+	err = mode.PageFlip(dev, crtc.ID, fbID, mode.PageFlipEvent, 8)
+	if !errors.Is(err, drm.ErrBusy) {
+		t.Errorf("Expected %v for a second flip but got %v", drm.ErrBusy, err)
+	}
+	if crtc.FbID != fbID {
+		t.Errorf("Flipped before the vblank")
+	}
+
+	card.VBlank()
+	events, err := drm.ReadEvents(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != drm.EventFlipComplete ||
+		events[0].UserData != 7 || events[0].CrtcID != crtc.ID ||
+		events[0].Sequence != 1 || events[0].Time != crtc.VBlankTime {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+	if c, err := dev.GetCrtc(crtc.ID); err != nil || c.BufferID != backID {
+		t.Errorf("Not flipped: %+v, %v", c, err)
+	}
+
+	other := openFake(t, card)
+	for _, test := range []struct {
+		name     string
+		file     *drm.Device
+		crtcID   uint32
+		fbID     uint32
+		flags    uint32
+		expected error
+	}{
+		{"not master", other, crtc.ID, fbID, 0, drm.ErrNotMaster},
+		{"no crtc", dev, 1000, fbID, 0, drm.ErrNotFound},
+		{"no fb", dev, crtc.ID, 1000, 0, drm.ErrNotFound},
+		{"inactive", dev, card.AddCrtc().ID, fbID, 0, drm.ErrInvalidMode},
+		{"async", dev, crtc.ID, fbID, mode.PageFlipAsync, drm.ErrInvalidMode},
+		{"target", dev, crtc.ID, fbID, mode.PageFlipTargetRelative, drm.ErrInvalidMode},
+	} {
+		err := mode.PageFlip(test.file, test.crtcID, test.fbID, test.flags, 0)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestAtomicFlipEvent(t *testing.T) {
+	card, dev, conn, primary, fbID := atomicCard(t)
+	crtc := card.Crtcs[0]
+	info := conn.Modes[0]
+	if err := dev.SetCrtc(crtc.ID, fbID, 0, 0, &conn.ID, 1, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	req := mode.NewAtomicRequest(dev)
+	req.UserData = 99
+	addProps(t, req, primary.ID, map[string]uint64{"FB_ID": uint64(fbID)})
+	if err := req.Commit(mode.PageFlipEvent | mode.AtomicNonblock); err != nil {
+		t.Fatal(err)
+	}
+	err := req.Commit(mode.PageFlipEvent | mode.AtomicNonblock)
+	if !errors.Is(err, drm.ErrBusy) {
+		t.Errorf("Expected %v with a pending commit but got %v", drm.ErrBusy, err)
+	}
+
+	card.VBlank()
+	card.VBlank()
+	events, err := dev.ReadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].UserData != 99 ||
+		events[0].CrtcID != crtc.ID || events[0].Sequence != 1 {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+	if crtc.Sequence != 2 {
+		t.Errorf("Unexpected sequence %d after 2 vblanks", crtc.Sequence)
+	}
+}
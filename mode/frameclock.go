@@ -0,0 +1,166 @@
+package mode
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// FrameClock tracks the vblanks of a CRTC to pace the frames shown
+	// on it. It starts from the refresh period of the mode timings and
+	// corrects it with the vblanks observed, usually from the flip
+	// complete events:
+	//
+	//	clock := mode.NewFrameClock(&crtc.Mode)
+	//	...
+	//	seq, at := clock.Next(now)
+	//	// render the frame due at time at, flip it
+	//	...
+	//	// on its flip complete event:
+	//	clock.Presented(seq, ev.Sequence, ev.Time)
+	//
+	// The vblank times are in the clock of the events, CLOCK_MONOTONIC
+	// if the driver has drm.CapTimestampMonotonic. A FrameClock is safe
+	// to use from many goroutines.
+	FrameClock struct {
+		mu      sync.Mutex
+		nominal time.Duration // from the mode timings
+		period  time.Duration // estimated
+
+		// last vblank observed
+		observed bool
+		seq      uint64
+		time     time.Duration
+
+		stats FrameStats
+	}
+
+	// FrameStats counts the frames presented through a FrameClock.
+	FrameStats struct {
+		Presented uint64 // frames presented
+		Late      uint64 // frames presented after their target vblank
+		Missed    uint64 // vblanks the late frames missed, in total
+	}
+)
+
+// RefreshPeriod returns the time between two vblanks of the mode,
+// computed from its timings like the kernel does, or zero if the mode
+// has no pixel clock. Interlaced modes have a vblank per field, half a
+// frame.
+func (info *Info) RefreshPeriod() time.Duration {
+	if info.Clock == 0 {
+		return 0
+	}
+	vtotal := uint64(info.Vtotal)
+	if info.Flags&FlagDblScan != 0 {
+		vtotal *= 2
+	}
+	if info.Vscan > 1 {
+		vtotal *= uint64(info.Vscan)
+	}
+	// the clock is in kHz
+	period := uint64(info.Htotal) * vtotal * 1000000 / uint64(info.Clock)
+	if info.Flags&FlagInterlace != 0 {
+		period /= 2
+	}
+	return time.Duration(period)
+}
+
+// NewFrameClock returns a frame clock of a CRTC scanning out the mode
+// info. It cannot predict vblanks before observing one, see
+// drm.GetCrtcSequence.
+func NewFrameClock(info *Info) *FrameClock {
+	period := info.RefreshPeriod()
+	return &FrameClock{
+		nominal: period,
+		period:  period,
+	}
+}
+
+// Period returns the estimated time between two vblanks.
+func (c *FrameClock) Period() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.period
+}
+
+// Observe feeds the clock with the vblank sequence of the CRTC, that
+// happened at time t. Vblanks too far from the expected period, as
+// after the CRTC was off, only reset the reference of the predictions.
+func (c *FrameClock) Observe(sequence uint64, t time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observe(sequence, t)
+}
+
+func (c *FrameClock) observe(sequence uint64, t time.Duration) {
+	if c.observed && sequence <= c.seq {
+		return // an old vblank, eg.: reported by a late event
+	}
+	if c.observed && t > c.time {
+		measured := (t - c.time) / time.Duration(sequence-c.seq)
+		ref := c.nominal
+		if ref == 0 {
+			ref = c.period
+		}
+		switch {
+		case ref == 0:
+			c.period = measured
+		case abs(measured-ref) <= ref/8:
+			// moving average, weighting the recent vblanks more
+			c.period += (measured - c.period) / 8
+		}
+	}
+	c.observed = true
+	c.seq, c.time = sequence, t
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// TimeOf returns the predicted time of the vblank sequence.
+func (c *FrameClock) TimeOf(sequence uint64) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.time + time.Duration(int64(sequence-c.seq))*c.period
+}
+
+// Next returns the first vblank predicted after the time now, with its
+// predicted time: the vblank to present a frame due at now.
+func (c *FrameClock) Next(now time.Duration) (uint64, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.period <= 0 || now < c.time {
+		return c.seq + 1, c.time + c.period
+	}
+	n := uint64((now-c.time)/c.period) + 1
+	return c.seq + n, c.time + time.Duration(n)*c.period
+}
+
+// Presented accounts a frame targeted at the vblank target, presented
+// at the vblank sequence at time t, and observes that vblank. It
+// returns the number of vblanks the frame missed.
+func (c *FrameClock) Presented(target, sequence uint64, t time.Duration) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observe(sequence, t)
+	c.stats.Presented++
+	if sequence <= target {
+		return 0
+	}
+	missed := sequence - target
+	c.stats.Late++
+	c.stats.Missed += missed
+	return missed
+}
+
+// Stats returns the counts of the frames presented.
+func (c *FrameClock) Stats() FrameStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
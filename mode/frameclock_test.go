@@ -0,0 +1,83 @@
+package mode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestRefreshPeriod(t *testing.T) {
+	info := mode.Info{Clock: 148500, Htotal: 2200, Vtotal: 1125}
+	if period := info.RefreshPeriod(); period != 16666666 {
+		t.Errorf("Unexpected period of 1080p60: %v", period)
+	}
+	info.Flags = mode.FlagInterlace
+	if period := info.RefreshPeriod(); period != 8333333 {
+		t.Errorf("Unexpected period of 1080i: %v", period)
+	}
+	info = mode.Info{Clock: 25175, Htotal: 800, Vtotal: 525, Vscan: 2}
+	info.Flags = mode.FlagDblScan
+	if period := info.RefreshPeriod(); period != 66732869 {
+		t.Errorf("Unexpected period of a scanned mode: %v", period)
+	}
+	if period := (&mode.Info{}).RefreshPeriod(); period != 0 {
+		t.Errorf("Expected no period without clock but got %v", period)
+	}
+}
+
+// near tells whether the durations a and b are within a microsecond.
+func near(a, b time.Duration) bool {
+	return a-b >= -time.Microsecond && a-b <= time.Microsecond
+}
+
+func TestFrameClock(t *testing.T) {
+	info := drmtest.Mode(1920, 1080, 60)
+	clock := mode.NewFrameClock(&info)
+	nominal := clock.Period()
+	if nominal != info.RefreshPeriod() {
+		t.Fatalf("Unexpected initial period %v", nominal)
+	}
+
+	// the display runs a bit slower than its mode says
+	actual := nominal + 50*time.Microsecond
+	start := 10 * time.Second
+	for seq := uint64(100); seq < 200; seq++ {
+		clock.Observe(seq, start+time.Duration(seq-100)*actual)
+	}
+	if !near(clock.Period(), actual) {
+		t.Errorf("Period %v not corrected to %v", clock.Period(), actual)
+	}
+
+	last := start + 99*actual
+	if at := clock.TimeOf(201); !near(at, last+2*actual) {
+		t.Errorf("Unexpected time of 201: %v", at)
+	}
+	seq, at := clock.Next(last + actual/2)
+	if seq != 200 || !near(at, last+actual) {
+		t.Errorf("Unexpected next vblank %d at %v", seq, at)
+	}
+
+	// a gap after the CRTC was off: not a period
+	clock.Observe(500, last+time.Hour)
+	clock.Observe(300, last) // stale
+	if !near(clock.Period(), actual) {
+		t.Errorf("Period changed by a gap: %v", clock.Period())
+	}
+	if seq, _ := clock.Next(last + time.Hour); seq != 501 {
+		t.Errorf("Expected next vblank 501 but got %d", seq)
+	}
+
+	base := last + time.Hour
+	if missed := clock.Presented(501, 501, base+actual); missed != 0 {
+		t.Errorf("Frame on time missed %d vblanks", missed)
+	}
+	if missed := clock.Presented(502, 504, base+4*actual); missed != 2 {
+		t.Errorf("Expected 2 vblanks missed but got %d", missed)
+	}
+	stats := clock.Stats()
+	if stats != (mode.FrameStats{Presented: 2, Late: 1, Missed: 2}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
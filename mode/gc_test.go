@@ -0,0 +1,96 @@
+package mode_test
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// TestGarbageCollection checks that the buffers referenced by the
+// request arguments are kept alive until the requests return. The test
+// runs itself again with GOGC=1 and GODEBUG=clobberfree=1, so that
+// memory collected while still in use by the fake card is overwritten,
+// and the card forces a collection before each request.
+func TestGarbageCollection(t *testing.T) {
+	if os.Getenv("DRMTEST_GC") == "" {
+		if testing.Short() {
+			t.Skip("skipping in short mode")
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestGarbageCollection$")
+		cmd.Env = append(os.Environ(), "DRMTEST_GC=1", "GOGC=1",
+			"GODEBUG=clobberfree=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s:\n%s", err, out)
+		}
+		return
+	}
+
+	card := drmtest.New()
+	modes := []mode.Info{
+		drmtest.Mode(1920, 1080, 60),
+		drmtest.Mode(1280, 720, 60),
+		drmtest.Mode(640, 480, 60),
+	}
+	fake := card.AddHead(modes...)
+	fake.Props = []uint32{1, 2, 3}
+	fake.PropValues = []uint64{4, 5, 6}
+	card.AddHead()
+	dev := openFake(t, card)
+	card.CollectGarbage = true
+
+	for i := 0; i < 20; i++ {
+		version, err := drm.GetVersion(dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version.Name != card.Version.Name ||
+			version.Desc != card.Version.Desc ||
+			version.Date != card.Version.Date {
+			t.Fatalf("Unexpected version: %+v", version)
+		}
+
+		res, err := mode.GetResources(dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Connectors) != 2 || res.Connectors[0] != fake.ID {
+			t.Fatalf("Unexpected resources: %+v", res)
+		}
+
+		conn, err := mode.GetConnector(dev, fake.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(conn.Modes, modes) ||
+			!reflect.DeepEqual(conn.PropValues, fake.PropValues) {
+			t.Fatalf("Unexpected connector: %+v", conn)
+		}
+
+		fb, err := mode.CreateFB(dev, 640, 480, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fbID, err := mode.AddFB(dev, 640, 480, 24, 32, fb.Pitch, fb.Handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		connectors := []uint32{fake.ID}
+		err = mode.SetCrtc(dev, res.Crtcs[0], fbID, 0, 0, &connectors[0],
+			len(connectors), &modes[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mode.RmFB(dev, fbID); err != nil {
+			t.Fatal(err)
+		}
+		if err := mode.DestroyDumb(dev, fb.Handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
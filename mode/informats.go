@@ -0,0 +1,124 @@
+package mode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+type (
+	// struct drm_format_modifier_blob, followed by the formats and the
+	// modifiers at their offsets
+	sysFormatModifierBlob struct {
+		version         uint32
+		flags           uint32
+		countFormats    uint32
+		formatsOffset   uint32
+		countModifiers  uint32
+		modifiersOffset uint32
+	}
+
+	// struct drm_format_modifier: the bit i of formats is set if the
+	// modifier can be used with the format at offset+i
+	sysFormatModifier struct {
+		formats  uint64
+		offset   uint32
+		pad      uint32
+		modifier uint64
+	}
+)
+
+// formatBlobVersion is the version of the IN_FORMATS blob layout.
+const formatBlobVersion = 1
+
+// ParseInFormats decodes the IN_FORMATS property blob of a plane into
+// the modifiers usable with each of its formats, in the order of the
+// blob. The formats without modifiers are listed with none.
+func ParseInFormats(data []byte) (map[fourcc.Format][]fourcc.Modifier, error) {
+	var hdr sysFormatModifierBlob
+	if len(data) < int(unsafe.Sizeof(hdr)) {
+		return nil, fmt.Errorf("mode: IN_FORMATS blob too short: %d bytes",
+			len(data))
+	}
+	hdr.version = binary.NativeEndian.Uint32(data[0:])
+	hdr.countFormats = binary.NativeEndian.Uint32(data[8:])
+	hdr.formatsOffset = binary.NativeEndian.Uint32(data[12:])
+	hdr.countModifiers = binary.NativeEndian.Uint32(data[16:])
+	hdr.modifiersOffset = binary.NativeEndian.Uint32(data[20:])
+	if hdr.version != formatBlobVersion {
+		return nil, fmt.Errorf("mode: unknown IN_FORMATS blob version %d",
+			hdr.version)
+	}
+
+	formatsEnd := uint64(hdr.formatsOffset) + uint64(hdr.countFormats)*4
+	modSize := uint64(unsafe.Sizeof(sysFormatModifier{}))
+	modifiersEnd := uint64(hdr.modifiersOffset) +
+		uint64(hdr.countModifiers)*modSize
+	if formatsEnd > uint64(len(data)) || modifiersEnd > uint64(len(data)) {
+		return nil, fmt.Errorf("mode: IN_FORMATS blob of %d bytes "+
+			"truncated, %d formats at %d and %d modifiers at %d",
+			len(data), hdr.countFormats, hdr.formatsOffset,
+			hdr.countModifiers, hdr.modifiersOffset)
+	}
+
+	formats := make([]fourcc.Format, hdr.countFormats)
+	ret := make(map[fourcc.Format][]fourcc.Modifier, len(formats))
+	for i := range formats {
+		off := uint64(hdr.formatsOffset) + uint64(i)*4
+		formats[i] = fourcc.Format(binary.NativeEndian.Uint32(data[off:]))
+		ret[formats[i]] = nil
+	}
+	for i := uint64(0); i < uint64(hdr.countModifiers); i++ {
+		entry := data[uint64(hdr.modifiersOffset)+i*modSize:]
+		mod := sysFormatModifier{
+			formats:  binary.NativeEndian.Uint64(entry[0:]),
+			offset:   binary.NativeEndian.Uint32(entry[8:]),
+			modifier: binary.NativeEndian.Uint64(entry[16:]),
+		}
+		for bit := uint64(0); bit < 64; bit++ {
+			if mod.formats&(1<<bit) == 0 {
+				continue
+			}
+			idx := uint64(mod.offset) + bit
+			if idx >= uint64(len(formats)) {
+				return nil, fmt.Errorf("mode: IN_FORMATS modifier %s "+
+					"of format %d, the plane has %d",
+					fourcc.Modifier(mod.modifier), idx, len(formats))
+			}
+			format := formats[idx]
+			ret[format] = append(ret[format], fourcc.Modifier(mod.modifier))
+		}
+	}
+	return ret, nil
+}
+
+// GetInFormats returns the modifiers usable with each format of the
+// plane planeID, from its IN_FORMATS property. The planes of the
+// drivers without drm.CapAddFB2Modifiers have no IN_FORMATS, and fail
+// with ErrNoProperty: they take their formats, see GetPlane, only in
+// the fourcc.ModLinear layout.
+func GetInFormats(file ioctl.File, planeID uint32) (map[fourcc.Format][]fourcc.Modifier, error) {
+	obj, err := GetObjectProperties(file, planeID, ObjectPlane)
+	if err != nil {
+		return nil, err
+	}
+	for i, prop := range obj.Props {
+		name, err := propertyName(file, prop)
+		if err != nil {
+			return nil, err
+		}
+		if name != "IN_FORMATS" {
+			continue
+		}
+		data, err := GetBlob(file, uint32(obj.PropValues[i]))
+		if err != nil {
+			return nil, err
+		}
+		return ParseInFormats(data)
+	}
+	return nil, fmt.Errorf("%w: plane %d has no property \"IN_FORMATS\"",
+		ErrNoProperty, planeID)
+}
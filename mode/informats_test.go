@@ -0,0 +1,130 @@
+package mode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+// skylakeInFormats is the IN_FORMATS blob of a Skylake primary plane,
+// as the i915 driver lays it out on x86: the CCS modifiers are only
+// for the 8888 RGB formats, and the C8 and NV12 formats have no Yf
+// tiling.
+var skylakeInFormats = []byte{
+	// header: version, flags, 10 formats at 24, 6 modifiers at 64
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x0a, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00,
+	0x06, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00,
+	// C8, RG16, XR24, XB24, AR24, AB24, XR30, XB30, YUYV, NV12
+	0x43, 0x38, 0x20, 0x20, 0x52, 0x47, 0x31, 0x36,
+	0x58, 0x52, 0x32, 0x34, 0x58, 0x42, 0x32, 0x34,
+	0x41, 0x52, 0x32, 0x34, 0x41, 0x42, 0x32, 0x34,
+	0x58, 0x52, 0x33, 0x30, 0x58, 0x42, 0x33, 0x30,
+	0x59, 0x55, 0x59, 0x56, 0x4e, 0x56, 0x31, 0x32,
+	// I915_Yf_TILED_CCS
+	0x3c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	// I915_Y_TILED_CCS
+	0x3c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	// I915_Yf_TILED
+	0xfe, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	// I915_Y_TILED
+	0xfe, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	// I915_X_TILED
+	0xff, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	// LINEAR
+	0xff, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestParseInFormats(t *testing.T) {
+	formats, err := mode.ParseInFormats(skylakeInFormats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccs := []fourcc.Modifier{
+		fourcc.ModI915YfTiledCCS, fourcc.ModI915YTiledCCS,
+		fourcc.ModI915YfTiled, fourcc.ModI915YTiled, fourcc.ModI915XTiled,
+		fourcc.ModLinear,
+	}
+	tiled := ccs[2:]
+	expected := map[fourcc.Format][]fourcc.Modifier{
+		fourcc.C8:          {fourcc.ModI915XTiled, fourcc.ModLinear},
+		fourcc.RGB565:      tiled,
+		fourcc.XRGB8888:    ccs,
+		fourcc.XBGR8888:    ccs,
+		fourcc.ARGB8888:    ccs,
+		fourcc.ABGR8888:    ccs,
+		fourcc.XRGB2101010: tiled,
+		fourcc.XBGR2101010: tiled,
+		fourcc.YUYV:        tiled,
+		fourcc.NV12:        tiled[1:],
+	}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("Expected %v but got %v", expected, formats)
+	}
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short header", skylakeInFormats[:20]},
+		{"truncated", skylakeInFormats[:len(skylakeInFormats)-1]},
+		{"version", append([]byte{2}, skylakeInFormats[1:]...)},
+		{
+			"format past the formats",
+			append(append([]byte(nil), skylakeInFormats[:64]...),
+				0x00, 0x04, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 0),
+		},
+	} {
+		if _, err := mode.ParseInFormats(test.data); err == nil {
+			t.Errorf("Expected error decoding the %s blob", test.name)
+		}
+	}
+}
+
+func TestGetInFormats(t *testing.T) {
+	card := drmtest.New()
+	card.AddHead(drmtest.Mode(1920, 1080, 60))
+	primary := card.AddPlane(mode.PlanePrimary, 1, fourcc.XRGB8888, fourcc.NV12)
+	overlay := card.AddPlane(mode.PlaneOverlay, 1, fourcc.XRGB8888)
+	afbc := fourcc.ModARMAFBC(fourcc.AFBCBlockSize16x16 | fourcc.AFBCSparse)
+	card.AddInFormats(primary, []fourcc.Modifier{afbc, fourcc.ModLinear},
+		func(format fourcc.Format, mod fourcc.Modifier) bool {
+			return mod == fourcc.ModLinear || format == fourcc.XRGB8888
+		})
+	dev := openFake(t, card)
+
+	formats, err := mode.GetInFormats(dev, primary.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[fourcc.Format][]fourcc.Modifier{
+		fourcc.XRGB8888: {afbc, fourcc.ModLinear},
+		fourcc.NV12:     {fourcc.ModLinear},
+	}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("Expected %v but got %v", expected, formats)
+	}
+
+	if _, err := mode.GetInFormats(dev, overlay.ID); !errors.Is(err, mode.ErrNoProperty) {
+		t.Errorf("Expected %v but got %v", mode.ErrNoProperty, err)
+	}
+}
@@ -1,13 +1,14 @@
 package mode
 
 import (
-	"os"
 	"unsafe"
 
-	"github.com/NeowayLabs/drm"
 	"github.com/NeowayLabs/drm/ioctl"
 )
 
+// same as ioctlBase, the drm package imports this one.
+const ioctlBase = 'd'
+
 const (
 	DisplayInfoLen   = 32
 	ConnectorNameLen = 32
@@ -19,6 +20,73 @@ const (
 	UnknownConnection = 3
 )
 
+// Info.Flags
+const (
+	FlagPHSync = 1 << iota
+	FlagNHSync
+	FlagPVSync
+	FlagNVSync
+	FlagInterlace
+	FlagDblScan
+	FlagCSync
+	FlagPCSync
+	FlagNCSync
+	FlagHSkew
+	FlagBCast
+	FlagPixMux
+	FlagDblClk
+	FlagClkDiv2
+)
+
+// Info.Type
+const (
+	TypeBuiltin = 1 << iota
+	TypeClockC
+	TypeCrtcC
+	TypePreferred
+	TypeDefault
+	TypeUserDef
+	TypeDriver
+)
+
+// Encoder.Type
+const (
+	EncoderNone = iota
+	EncoderDAC
+	EncoderTMDS
+	EncoderLVDS
+	EncoderTVDAC
+	EncoderVirtual
+	EncoderDSI
+	EncoderDPMST
+	EncoderDPI
+)
+
+// Connector.Type
+const (
+	ConnectorUnknown = iota
+	ConnectorVGA
+	ConnectorDVII
+	ConnectorDVID
+	ConnectorDVIA
+	ConnectorComposite
+	ConnectorSVIDEO
+	ConnectorLVDS
+	ConnectorComponent
+	Connector9PinDIN
+	ConnectorDisplayPort
+	ConnectorHDMIA
+	ConnectorHDMIB
+	ConnectorTV
+	ConnectoreDP
+	ConnectorVirtual
+	ConnectorDSI
+	ConnectorDPI
+	ConnectorWriteback
+	ConnectorSPI
+	ConnectorUSB
+)
+
 type (
 	sysResources struct {
 		fbIdPtr              uint64
@@ -188,82 +256,105 @@ type (
 
 var (
 	// DRM_IOWR(0xA0, struct drm_mode_card_res)
-	IOCTLModeResources = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysResources{})), drm.IOCTLBase, 0xA0)
+	IOCTLModeResources = ioctl.Register("DRM_IOCTL_MODE_GETRESOURCES",
+		ioctl.IOWR(ioctlBase, 0xA0, sysResources{}))
 
 	// DRM_IOWR(0xA1, struct drm_mode_crtc)
-	IOCTLModeGetCrtc = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysCrtc{})), drm.IOCTLBase, 0xA1)
+	IOCTLModeGetCrtc = ioctl.Register("DRM_IOCTL_MODE_GETCRTC",
+		ioctl.IOWR(ioctlBase, 0xA1, sysCrtc{}))
 
 	// DRM_IOWR(0xA2, struct drm_mode_crtc)
-	IOCTLModeSetCrtc = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysCrtc{})), drm.IOCTLBase, 0xA2)
+	IOCTLModeSetCrtc = ioctl.Register("DRM_IOCTL_MODE_SETCRTC",
+		ioctl.IOWR(ioctlBase, 0xA2, sysCrtc{}))
 
 	// DRM_IOWR(0xA6, struct drm_mode_get_encoder)
-	IOCTLModeGetEncoder = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysGetEncoder{})), drm.IOCTLBase, 0xA6)
+	IOCTLModeGetEncoder = ioctl.Register("DRM_IOCTL_MODE_GETENCODER",
+		ioctl.IOWR(ioctlBase, 0xA6, sysGetEncoder{}))
 
 	// DRM_IOWR(0xA7, struct drm_mode_get_connector)
-	IOCTLModeGetConnector = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysGetConnector{})), drm.IOCTLBase, 0xA7)
+	IOCTLModeGetConnector = ioctl.Register("DRM_IOCTL_MODE_GETCONNECTOR",
+		ioctl.IOWR(ioctlBase, 0xA7, sysGetConnector{}))
 
 	// DRM_IOWR(0xAE, struct drm_mode_fb_cmd)
-	IOCTLModeAddFB = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysFBCmd{})), drm.IOCTLBase, 0xAE)
+	IOCTLModeAddFB = ioctl.Register("DRM_IOCTL_MODE_ADDFB",
+		ioctl.IOWR(ioctlBase, 0xAE, sysFBCmd{}))
 
 	// DRM_IOWR(0xAF, unsigned int)
-	IOCTLModeRmFB = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(uint32(0))), drm.IOCTLBase, 0xAF)
+	IOCTLModeRmFB = ioctl.Register("DRM_IOCTL_MODE_RMFB",
+		ioctl.IOWR(ioctlBase, 0xAF, uint32(0)))
 
 	// DRM_IOWR(0xB2, struct drm_mode_create_dumb)
-	IOCTLModeCreateDumb = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysCreateDumb{})), drm.IOCTLBase, 0xB2)
+	IOCTLModeCreateDumb = ioctl.Register("DRM_IOCTL_MODE_CREATE_DUMB",
+		ioctl.IOWR(ioctlBase, 0xB2, sysCreateDumb{}))
 
 	// DRM_IOWR(0xB3, struct drm_mode_map_dumb)
-	IOCTLModeMapDumb = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysMapDumb{})), drm.IOCTLBase, 0xB3)
+	IOCTLModeMapDumb = ioctl.Register("DRM_IOCTL_MODE_MAP_DUMB",
+		ioctl.IOWR(ioctlBase, 0xB3, sysMapDumb{}))
 
 	// DRM_IOWR(0xB4, struct drm_mode_destroy_dumb)
-	IOCTLModeDestroyDumb = ioctl.NewCode(ioctl.Read|ioctl.Write,
-		uint16(unsafe.Sizeof(sysDestroyDumb{})), drm.IOCTLBase, 0xB4)
+	IOCTLModeDestroyDumb = ioctl.Register("DRM_IOCTL_MODE_DESTROY_DUMB",
+		ioctl.IOWR(ioctlBase, 0xB4, sysDestroyDumb{}))
 )
 
-func GetResources(file *os.File) (*Resources, error) {
-	mres := &sysResources{}
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeResources),
-		uintptr(unsafe.Pointer(mres)))
-	if err != nil {
-		return nil, err
-	}
+func GetResources(file ioctl.File) (*Resources, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
 
 	var (
+		mres                                     *sysResources
 		fbids, crtcids, connectorids, encoderids []uint32
 	)
 
-	if mres.CountFbs > 0 {
-		fbids = make([]uint32, mres.CountFbs)
-		mres.fbIdPtr = uint64(uintptr(unsafe.Pointer(&fbids[0])))
-	}
-	if mres.CountCrtcs > 0 {
-		crtcids = make([]uint32, mres.CountCrtcs)
-		mres.crtcIdPtr = uint64(uintptr(unsafe.Pointer(&crtcids[0])))
-	}
-	if mres.CountEncoders > 0 {
-		encoderids = make([]uint32, mres.CountEncoders)
-		mres.encoderIdPtr = uint64(uintptr(unsafe.Pointer(&encoderids[0])))
-	}
-	if mres.CountConnectors > 0 {
-		connectorids = make([]uint32, mres.CountConnectors)
-		mres.connectorIdPtr = uint64(uintptr(unsafe.Pointer(&connectorids[0])))
-	}
-
-	err = ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeResources),
-		uintptr(unsafe.Pointer(mres)))
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO(i4k): handle hotplugging in-between the ioctls above
+	// Objects can be hotplugged in-between the two ioctls below, then
+	// the kernel fills only part of the arrays (or none of them) and
+	// returns the new counts. Retry until the counts are stable, like
+	// libdrm does.
+	for {
+		mres = &sysResources{}
+		err := ioctl.Call(file, uintptr(IOCTLModeResources),
+			pins.Ptr(unsafe.Pointer(mres)))
+		if err != nil {
+			return nil, err
+		}
+		counts := *mres
+
+		fbids, crtcids, connectorids, encoderids = nil, nil, nil, nil
+		if mres.CountFbs > 0 {
+			fbids = make([]uint32, mres.CountFbs)
+			mres.fbIdPtr = pins.Addr(unsafe.Pointer(&fbids[0]))
+		}
+		if mres.CountCrtcs > 0 {
+			crtcids = make([]uint32, mres.CountCrtcs)
+			mres.crtcIdPtr = pins.Addr(unsafe.Pointer(&crtcids[0]))
+		}
+		if mres.CountEncoders > 0 {
+			encoderids = make([]uint32, mres.CountEncoders)
+			mres.encoderIdPtr = pins.Addr(unsafe.Pointer(&encoderids[0]))
+		}
+		if mres.CountConnectors > 0 {
+			connectorids = make([]uint32, mres.CountConnectors)
+			mres.connectorIdPtr = pins.Addr(unsafe.Pointer(&connectorids[0]))
+		}
+
+		err = ioctl.Call(file, uintptr(IOCTLModeResources),
+			pins.Ptr(unsafe.Pointer(mres)))
+		if err != nil {
+			return nil, err
+		}
+
+		if mres.CountFbs <= counts.CountFbs &&
+			mres.CountCrtcs <= counts.CountCrtcs &&
+			mres.CountEncoders <= counts.CountEncoders &&
+			mres.CountConnectors <= counts.CountConnectors {
+			break
+		}
+	}
+
+	// objects could also be removed
+	fbids = fbids[:mres.CountFbs]
+	crtcids = crtcids[:mres.CountCrtcs]
+	encoderids = encoderids[:mres.CountEncoders]
+	connectorids = connectorids[:mres.CountConnectors]
 
 	return &Resources{
 		sysResources: *mres,
@@ -274,46 +365,67 @@ func GetResources(file *os.File) (*Resources, error) {
 	}, nil
 }
 
-func GetConnector(file *os.File, connid uint32) (*Connector, error) {
-	conn := &sysGetConnector{}
-	conn.ID = connid
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeGetConnector),
-		uintptr(unsafe.Pointer(conn)))
-	if err != nil {
-		return nil, err
-	}
+func GetConnector(file ioctl.File, connid uint32) (*Connector, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
 
 	var (
+		conn            *sysGetConnector
 		props, encoders []uint32
 		propValues      []uint64
 		modes           []Info
 	)
 
-	if conn.countProps > 0 {
-		props = make([]uint32, conn.countProps)
-		conn.propsPtr = uint64(uintptr(unsafe.Pointer(&props[0])))
-
-		propValues = make([]uint64, conn.countProps)
-		conn.propValuesPtr = uint64(uintptr(unsafe.Pointer(&propValues[0])))
-	}
-
-	if conn.countModes == 0 {
-		conn.countModes = 1
-	}
-
-	modes = make([]Info, conn.countModes)
-	conn.modesPtr = uint64(uintptr(unsafe.Pointer(&modes[0])))
-
-	if conn.countEncoders > 0 {
-		encoders = make([]uint32, conn.countEncoders)
-		conn.encodersPtr = uint64(uintptr(unsafe.Pointer(&encoders[0])))
-	}
-
-	err = ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeGetConnector),
-		uintptr(unsafe.Pointer(conn)))
-	if err != nil {
-		return nil, err
-	}
+	// same hotplug race of GetResources: a monitor plugged in-between
+	// the ioctls changes the number of modes (and properties).
+	for {
+		conn = &sysGetConnector{}
+		conn.ID = connid
+		err := ioctl.Call(file, uintptr(IOCTLModeGetConnector),
+			pins.Ptr(unsafe.Pointer(conn)))
+		if err != nil {
+			return nil, err
+		}
+
+		props, encoders, propValues = nil, nil, nil
+		if conn.countProps > 0 {
+			props = make([]uint32, conn.countProps)
+			conn.propsPtr = pins.Addr(unsafe.Pointer(&props[0]))
+
+			propValues = make([]uint64, conn.countProps)
+			conn.propValuesPtr = pins.Addr(unsafe.Pointer(&propValues[0]))
+		}
+
+		if conn.countModes == 0 {
+			conn.countModes = 1
+		}
+
+		modes = make([]Info, conn.countModes)
+		conn.modesPtr = pins.Addr(unsafe.Pointer(&modes[0]))
+
+		if conn.countEncoders > 0 {
+			encoders = make([]uint32, conn.countEncoders)
+			conn.encodersPtr = pins.Addr(unsafe.Pointer(&encoders[0]))
+		}
+		counts := *conn
+
+		err = ioctl.Call(file, uintptr(IOCTLModeGetConnector),
+			pins.Ptr(unsafe.Pointer(conn)))
+		if err != nil {
+			return nil, err
+		}
+
+		if conn.countProps <= counts.countProps &&
+			conn.countModes <= counts.countModes &&
+			conn.countEncoders <= counts.countEncoders {
+			break
+		}
+	}
+
+	props = props[:conn.countProps]
+	propValues = propValues[:conn.countProps]
+	modes = modes[:conn.countModes]
+	encoders = encoders[:conn.countEncoders]
 
 	ret := &Connector{
 		sysGetConnector: *conn,
@@ -341,12 +453,15 @@ func GetConnector(file *os.File, connid uint32) (*Connector, error) {
 	return ret, nil
 }
 
-func GetEncoder(file *os.File, id uint32) (*Encoder, error) {
+func GetEncoder(file ioctl.File, id uint32) (*Encoder, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	encoder := &sysGetEncoder{}
 	encoder.id = id
 
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeGetEncoder),
-		uintptr(unsafe.Pointer(encoder)))
+	err := ioctl.Call(file, uintptr(IOCTLModeGetEncoder),
+		pins.Ptr(unsafe.Pointer(encoder)))
 	if err != nil {
 		return nil, err
 	}
@@ -360,13 +475,16 @@ func GetEncoder(file *os.File, id uint32) (*Encoder, error) {
 	}, nil
 }
 
-func CreateFB(file *os.File, width, height uint16, bpp uint32) (*FB, error) {
+func CreateFB(file ioctl.File, width, height uint16, bpp uint32) (*FB, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	fb := &sysCreateDumb{}
 	fb.width = uint32(width)
 	fb.height = uint32(height)
 	fb.bpp = bpp
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeCreateDumb),
-		uintptr(unsafe.Pointer(fb)))
+	err := ioctl.Call(file, uintptr(IOCTLModeCreateDumb),
+		pins.Ptr(unsafe.Pointer(fb)))
 	if err != nil {
 		return nil, err
 	}
@@ -380,8 +498,11 @@ func CreateFB(file *os.File, width, height uint16, bpp uint32) (*FB, error) {
 	}, nil
 }
 
-func AddFB(file *os.File, width, height uint16,
+func AddFB(file ioctl.File, width, height uint16,
 	depth, bpp uint8, pitch, boHandle uint32) (uint32, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	f := &sysFBCmd{}
 	f.width = uint32(width)
 	f.height = uint32(height)
@@ -389,40 +510,52 @@ func AddFB(file *os.File, width, height uint16,
 	f.bpp = uint32(bpp)
 	f.depth = uint32(depth)
 	f.handle = boHandle
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeAddFB),
-		uintptr(unsafe.Pointer(f)))
+	err := ioctl.Call(file, uintptr(IOCTLModeAddFB),
+		pins.Ptr(unsafe.Pointer(f)))
 	if err != nil {
 		return 0, err
 	}
 	return f.fbID, nil
 }
 
-func RmFB(file *os.File, bufferid uint32) error {
-	return ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeRmFB),
-		uintptr(unsafe.Pointer(&sysRmFB{bufferid})))
+func RmFB(file ioctl.File, bufferid uint32) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	return ioctl.Call(file, uintptr(IOCTLModeRmFB),
+		pins.Ptr(unsafe.Pointer(&sysRmFB{bufferid})))
 }
 
-func MapDumb(file *os.File, boHandle uint32) (uint64, error) {
+func MapDumb(file ioctl.File, boHandle uint32) (uint64, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	mreq := &sysMapDumb{}
 	mreq.handle = boHandle
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeMapDumb),
-		uintptr(unsafe.Pointer(mreq)))
+	err := ioctl.Call(file, uintptr(IOCTLModeMapDumb),
+		pins.Ptr(unsafe.Pointer(mreq)))
 	if err != nil {
 		return 0, err
 	}
 	return mreq.offset, nil
 }
 
-func DestroyDumb(file *os.File, handle uint32) error {
-	return ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeDestroyDumb),
-		uintptr(unsafe.Pointer(&sysDestroyDumb{handle})))
+func DestroyDumb(file ioctl.File, handle uint32) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	return ioctl.Call(file, uintptr(IOCTLModeDestroyDumb),
+		pins.Ptr(unsafe.Pointer(&sysDestroyDumb{handle})))
 }
 
-func GetCrtc(file *os.File, id uint32) (*Crtc, error) {
+func GetCrtc(file ioctl.File, id uint32) (*Crtc, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	crtc := &sysCrtc{}
 	crtc.id = id
-	err := ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeGetCrtc),
-		uintptr(unsafe.Pointer(crtc)))
+	err := ioctl.Call(file, uintptr(IOCTLModeGetCrtc),
+		pins.Ptr(unsafe.Pointer(crtc)))
 	if err != nil {
 		return nil, err
 	}
@@ -441,20 +574,23 @@ func GetCrtc(file *os.File, id uint32) (*Crtc, error) {
 	return ret, nil
 }
 
-func SetCrtc(file *os.File, crtcid, bufferid, x, y uint32, connectors *uint32, count int, mode *Info) error {
+func SetCrtc(file ioctl.File, crtcid, bufferid, x, y uint32, connectors *uint32, count int, mode *Info) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
 	crtc := &sysCrtc{}
 	crtc.x = x
 	crtc.y = y
 	crtc.id = crtcid
 	crtc.fbID = bufferid
 	if connectors != nil {
-		crtc.setConnectorsPtr = uint64(uintptr(unsafe.Pointer(connectors)))
+		crtc.setConnectorsPtr = pins.Addr(unsafe.Pointer(connectors))
 	}
 	crtc.countConnectors = uint32(count)
 	if mode != nil {
 		crtc.mode = *mode
 		crtc.modeValid = 1
 	}
-	return ioctl.Do(uintptr(file.Fd()), uintptr(IOCTLModeSetCrtc),
-		uintptr(unsafe.Pointer(crtc)))
+	return ioctl.Call(file, uintptr(IOCTLModeSetCrtc),
+		pins.Ptr(unsafe.Pointer(crtc)))
 }
@@ -0,0 +1,322 @@
+package mode_test
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/ioctl"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func openFake(t *testing.T, card *drmtest.Card) *drm.Device {
+	dev, err := card.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dev.Close() })
+	return dev
+}
+
+func TestGetResources(t *testing.T) {
+	card := drmtest.New()
+	conn1 := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	conn2 := card.AddHead()
+	dev := openFake(t, card)
+
+	res, err := mode.GetResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Connectors, []uint32{conn1.ID, conn2.ID}) {
+		t.Errorf("Unexpected connectors: %v", res.Connectors)
+	}
+	if len(res.Crtcs) != 2 || len(res.Encoders) != 2 || len(res.Fbs) != 0 {
+		t.Errorf("Unexpected resources: %+v", res)
+	}
+	if res.CountCrtcs != 2 || res.CountConnectors != 2 {
+		t.Errorf("Unexpected counts: %d crtcs, %d connectors",
+			res.CountCrtcs, res.CountConnectors)
+	}
+}
+
+func TestGetConnector(t *testing.T) {
+	card := drmtest.New()
+	modes := []mode.Info{
+		drmtest.Mode(1920, 1080, 60),
+		drmtest.Mode(1280, 720, 60),
+	}
+	fake := card.AddHead(modes...)
+	fake.Props = []uint32{1, 2}
+	fake.PropValues = []uint64{10, 20}
+	dev := openFake(t, card)
+
+	conn, err := mode.GetConnector(dev, fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.ID != fake.ID || conn.Connection != mode.Connected ||
+		conn.Type != mode.ConnectorHDMIA || conn.TypeID != 1 {
+		t.Errorf("Unexpected connector: %+v", conn)
+	}
+	if !reflect.DeepEqual(conn.Modes, modes) {
+		t.Errorf("Unexpected modes: %v", conn.Modes)
+	}
+	if !reflect.DeepEqual(conn.Encoders, fake.Encoders) {
+		t.Errorf("Unexpected encoders: %v", conn.Encoders)
+	}
+	if !reflect.DeepEqual(conn.Props, fake.Props) ||
+		!reflect.DeepEqual(conn.PropValues, fake.PropValues) {
+		t.Errorf("Unexpected properties: %v = %v", conn.Props,
+			conn.PropValues)
+	}
+
+	if _, err := mode.GetConnector(dev, 1000); err == nil {
+		t.Errorf("Expected error for an invalid connector")
+	}
+}
+
+func TestSetCrtc(t *testing.T) {
+	card := drmtest.New()
+	info := drmtest.Mode(1024, 768, 60)
+	conn := card.AddHead(info)
+	crtcID := card.Crtcs[0].ID
+	dev := openFake(t, card)
+
+	fb, err := mode.CreateFB(dev, 1024, 768, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := mode.AddFB(dev, 1024, 768, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mode.MapDumb(dev, fb.Handle); err != nil {
+		t.Fatal(err)
+	}
+	err = mode.SetCrtc(dev, crtcID, fbID, 0, 0, &conn.ID, 1, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crtc, err := mode.GetCrtc(dev, crtcID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crtc.BufferID != fbID || crtc.ModeValid != 1 ||
+		crtc.Width != 1024 || crtc.Height != 768 || crtc.Mode != info {
+		t.Errorf("Unexpected CRTC: %+v", crtc)
+	}
+
+	encoder, err := mode.GetEncoder(dev, conn.Encoders[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoder.CrtcID != crtcID {
+		t.Errorf("Encoder not bound to CRTC %d: %+v", crtcID, encoder)
+	}
+
+	if err := mode.RmFB(dev, fbID); err != nil {
+		t.Fatal(err)
+	}
+	if err := mode.DestroyDumb(dev, fb.Handle); err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Framebuffers) != 0 || len(card.DumbBuffers) != 0 {
+		t.Errorf("Buffers left behind: %v %v", card.Framebuffers,
+			card.DumbBuffers)
+	}
+}
+
+func TestNewSimpleModeset(t *testing.T) {
+	card := drmtest.New()
+	conn1 := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	card.AddHead() // disconnected
+	conn3 := card.AddHead(drmtest.Mode(800, 600, 60))
+	dev := openFake(t, card)
+
+	mset, err := mode.NewSimpleModeset(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mset.Modesets) != 2 {
+		t.Fatalf("Expected 2 modesets but got %d", len(mset.Modesets))
+	}
+	crtcs := []uint32{card.Crtcs[0].ID, card.Crtcs[2].ID}
+	for i, conn := range []*drmtest.Connector{conn1, conn3} {
+		m := mset.Modesets[i]
+		if m.Conn != conn.ID || m.Crtc != crtcs[i] || m.Mode != conn.Modes[0] ||
+			m.Width != conn.Modes[0].Hdisplay ||
+			m.Height != conn.Modes[0].Vdisplay {
+			t.Errorf("Unexpected modeset: %+v", m)
+		}
+	}
+}
+
+func TestNewSimpleModesetPossibleCrtcs(t *testing.T) {
+	card := drmtest.New()
+	card.AddCrtc()
+	crtc := card.AddCrtc()
+	// the encoder can only drive the second CRTC
+	encoder := card.AddEncoder(mode.EncoderTMDS, 1<<1)
+	conn := card.AddConnector(mode.ConnectorHDMIA, []uint32{encoder.ID},
+		drmtest.Mode(1280, 720, 60))
+	dev := openFake(t, card)
+
+	mset, err := mode.NewSimpleModeset(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mset.Modesets) != 1 || mset.Modesets[0].Conn != conn.ID ||
+		mset.Modesets[0].Crtc != crtc.ID {
+		t.Errorf("Expected connector %d on CRTC %d but got %+v", conn.ID,
+			crtc.ID, mset.Modesets)
+	}
+}
+
+func TestInterruptedRequests(t *testing.T) {
+	card := drmtest.New()
+	info := drmtest.Mode(640, 480, 60)
+	conn := card.AddHead(info)
+	dev := openFake(t, card)
+
+	card.Inject(mode.IOCTLModeResources, syscall.EINTR, syscall.EAGAIN)
+	res, err := mode.GetResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Connectors) != 1 {
+		t.Errorf("Unexpected connectors: %v", res.Connectors)
+	}
+
+	fb, err := mode.CreateFB(dev, 640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := mode.AddFB(dev, 640, 480, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.Inject(mode.IOCTLModeSetCrtc, syscall.EINTR, syscall.EINTR, syscall.EINTR)
+	err = mode.SetCrtc(dev, res.Crtcs[0], fbID, 0, 0, &conn.ID, 1, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ioctl.SetDefaultRetry(ioctl.SetDefaultRetry(ioctl.Retry{Max: 2}))
+	card.Inject(mode.IOCTLModeSetCrtc, syscall.EINTR, syscall.EINTR, syscall.EINTR)
+	err = mode.SetCrtc(dev, res.Crtcs[0], fbID, 0, 0, &conn.ID, 1, &info)
+	if !errors.Is(err, syscall.EINTR) {
+		t.Errorf("Expected EINTR after 2 retries but got %v", err)
+	}
+}
+
+func TestGetResourcesHotplug(t *testing.T) {
+	card := drmtest.New()
+	conn1 := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	dev := openFake(t, card)
+
+	var (
+		calls int
+		conn2 *drmtest.Connector
+	)
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeResources {
+			return
+		}
+		calls++
+		if calls == 2 {
+			// plugged in-between the count and fill requests
+			conn2 = card.AddHead(drmtest.Mode(1280, 1024, 60))
+		}
+	}
+
+	res, err := mode.GetResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 requests but got %d", calls)
+	}
+	if !reflect.DeepEqual(res.Connectors, []uint32{conn1.ID, conn2.ID}) {
+		t.Errorf("Unexpected connectors: %v", res.Connectors)
+	}
+	if len(res.Crtcs) != 2 || len(res.Encoders) != 2 ||
+		res.CountConnectors != 2 {
+		t.Errorf("Unexpected resources: %+v", res)
+	}
+
+	// unplugged in-between: no retry, but no stale entries either
+	calls = 0
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeResources {
+			return
+		}
+		calls++
+		if calls == 2 {
+			card.Lock()
+			card.Connectors = card.Connectors[:1]
+			card.Unlock()
+		}
+	}
+	res, err = mode.GetResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || !reflect.DeepEqual(res.Connectors, []uint32{conn1.ID}) {
+		t.Errorf("Unexpected connectors %v after %d requests",
+			res.Connectors, calls)
+	}
+}
+
+func TestGetConnectorHotplug(t *testing.T) {
+	card := drmtest.New()
+	fake := card.AddHead()
+	dev := openFake(t, card)
+
+	modes := []mode.Info{
+		drmtest.Mode(1920, 1080, 60),
+		drmtest.Mode(1280, 720, 60),
+	}
+	var calls int
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeGetConnector {
+			return
+		}
+		calls++
+		if calls == 2 {
+			card.Lock()
+			fake.Connection = mode.Connected
+			fake.Modes = modes
+			fake.Props = []uint32{1}
+			fake.PropValues = []uint64{2}
+			card.Unlock()
+		}
+	}
+
+	conn, err := mode.GetConnector(dev, fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 requests but got %d", calls)
+	}
+	if conn.Connection != mode.Connected || !reflect.DeepEqual(conn.Modes, modes) ||
+		!reflect.DeepEqual(conn.Props, fake.Props) {
+		t.Errorf("Unexpected connector: %+v", conn)
+	}
+
+	// no modes at all
+	card.BeforeRequest = nil
+	fake.Modes = nil
+	conn, err = mode.GetConnector(dev, fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.Modes) != 0 {
+		t.Errorf("Expected no modes but got %v", conn.Modes)
+	}
+}
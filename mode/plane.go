@@ -0,0 +1,210 @@
+package mode
+
+import (
+	"image"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+// Plane types, values of the "type" plane property.
+const (
+	PlaneOverlay = iota
+	PlanePrimary
+	PlaneCursor
+)
+
+type (
+	sysGetPlaneRes struct {
+		planeIDPtr  uint64
+		countPlanes uint32
+	}
+
+	sysGetPlane struct {
+		id     uint32
+		crtcID uint32
+		fbID   uint32
+
+		possibleCrtcs uint32
+		gammaSize     uint32
+
+		countFormatTypes uint32
+		formatTypePtr    uint64
+	}
+
+	sysSetPlane struct {
+		planeID uint32
+		crtcID  uint32
+		fbID    uint32
+		flags   uint32
+
+		crtcX, crtcY int32
+		crtcW, crtcH uint32
+
+		// source in 16.16 fixed point, mind the order of h and w
+		srcX, srcY uint32
+		srcH, srcW uint32
+	}
+
+	PlaneResources struct {
+		Planes []uint32
+	}
+
+	Plane struct {
+		ID     uint32
+		CrtcID uint32 // 0 if the plane is disabled
+		FbID   uint32
+
+		PossibleCrtcs uint32 // bitmask of the CRTC indexes
+		GammaSize     int
+
+		Formats []fourcc.Format // supported pixel formats
+	}
+)
+
+var (
+	// DRM_IOWR(0xB5, struct drm_mode_get_plane_res)
+	IOCTLModeGetPlaneResources = ioctl.Register("DRM_IOCTL_MODE_GETPLANERESOURCES",
+		ioctl.IOWR(ioctlBase, 0xB5, sysGetPlaneRes{}))
+
+	// DRM_IOWR(0xB6, struct drm_mode_get_plane)
+	IOCTLModeGetPlane = ioctl.Register("DRM_IOCTL_MODE_GETPLANE",
+		ioctl.IOWR(ioctlBase, 0xB6, sysGetPlane{}))
+
+	// DRM_IOWR(0xB7, struct drm_mode_set_plane)
+	IOCTLModeSetPlane = ioctl.Register("DRM_IOCTL_MODE_SETPLANE",
+		ioctl.IOWR(ioctlBase, 0xB7, sysSetPlane{}))
+)
+
+// GetPlaneResources returns the planes of the card. Only overlay planes
+// are listed unless the client capability drm.ClientCapUniversalPlanes
+// is enabled.
+func GetPlaneResources(file ioctl.File) (*PlaneResources, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	var (
+		res    *sysGetPlaneRes
+		planes []uint32
+	)
+
+	// same two-phase pattern of GetResources
+	for {
+		res = &sysGetPlaneRes{}
+		err := ioctl.Call(file, uintptr(IOCTLModeGetPlaneResources),
+			pins.Ptr(unsafe.Pointer(res)))
+		if err != nil {
+			return nil, err
+		}
+		counts := *res
+
+		planes = nil
+		if res.countPlanes > 0 {
+			planes = make([]uint32, res.countPlanes)
+			res.planeIDPtr = pins.Addr(unsafe.Pointer(&planes[0]))
+		}
+
+		err = ioctl.Call(file, uintptr(IOCTLModeGetPlaneResources),
+			pins.Ptr(unsafe.Pointer(res)))
+		if err != nil {
+			return nil, err
+		}
+		if res.countPlanes <= counts.countPlanes {
+			break
+		}
+	}
+
+	return &PlaneResources{
+		Planes: planes[:res.countPlanes],
+	}, nil
+}
+
+func GetPlane(file ioctl.File, id uint32) (*Plane, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	var (
+		plane   *sysGetPlane
+		formats []fourcc.Format
+	)
+	for {
+		plane = &sysGetPlane{}
+		plane.id = id
+		err := ioctl.Call(file, uintptr(IOCTLModeGetPlane),
+			pins.Ptr(unsafe.Pointer(plane)))
+		if err != nil {
+			return nil, err
+		}
+		counts := *plane
+
+		formats = nil
+		if plane.countFormatTypes > 0 {
+			formats = make([]fourcc.Format, plane.countFormatTypes)
+			plane.formatTypePtr = pins.Addr(unsafe.Pointer(&formats[0]))
+		}
+
+		err = ioctl.Call(file, uintptr(IOCTLModeGetPlane),
+			pins.Ptr(unsafe.Pointer(plane)))
+		if err != nil {
+			return nil, err
+		}
+		if plane.countFormatTypes <= counts.countFormatTypes {
+			break
+		}
+	}
+
+	return &Plane{
+		ID:            plane.id,
+		CrtcID:        plane.crtcID,
+		FbID:          plane.fbID,
+		PossibleCrtcs: plane.possibleCrtcs,
+		GammaSize:     int(plane.gammaSize),
+		Formats:       formats[:plane.countFormatTypes],
+	}, nil
+}
+
+// SetPlane shows the area src of the framebuffer fbID on the plane,
+// scaled to the area dst of the CRTC crtcID. The plane is disabled if
+// fbID is zero. Only the DRM master can set planes.
+func SetPlane(file ioctl.File, planeID, crtcID, fbID uint32,
+	dst image.Rectangle, src FixedRect) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	req := &sysSetPlane{
+		planeID: planeID,
+		crtcID:  crtcID,
+		fbID:    fbID,
+		crtcX:   int32(dst.Min.X),
+		crtcY:   int32(dst.Min.Y),
+		crtcW:   uint32(dst.Dx()),
+		crtcH:   uint32(dst.Dy()),
+		srcX:    uint32(src.X),
+		srcY:    uint32(src.Y),
+		srcW:    uint32(src.W),
+		srcH:    uint32(src.H),
+	}
+	return ioctl.Call(file, uintptr(IOCTLModeSetPlane),
+		pins.Ptr(unsafe.Pointer(req)))
+}
+
+// GetPlaneType returns the type of the plane: PlaneOverlay,
+// PlanePrimary or PlaneCursor. Kernels older than 3.15 don't have the
+// "type" property, but only expose overlay planes.
+func GetPlaneType(file ioctl.File, id uint32) (uint64, error) {
+	obj, err := GetObjectProperties(file, id, ObjectPlane)
+	if err != nil {
+		return 0, err
+	}
+	for i, prop := range obj.Props {
+		name, err := propertyName(file, prop)
+		if err != nil {
+			return 0, err
+		}
+		if name == "type" {
+			return obj.PropValues[i], nil
+		}
+	}
+	return PlaneOverlay, nil
+}
@@ -0,0 +1,191 @@
+package mode_test
+
+import (
+	"errors"
+	"image"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/fourcc"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestGetPlanes(t *testing.T) {
+	card := drmtest.New()
+	card.AddHead(drmtest.Mode(1920, 1080, 60))
+	primary := card.AddPlane(mode.PlanePrimary, 1, fourcc.XRGB8888, fourcc.ARGB8888)
+	overlay := card.AddPlane(mode.PlaneOverlay, 1, fourcc.XRGB8888, fourcc.NV12)
+	cursor := card.AddPlane(mode.PlaneCursor, 1, fourcc.ARGB8888)
+	overlay.CrtcID = card.Crtcs[0].ID
+	dev := openFake(t, card)
+
+	res, err := mode.GetPlaneResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Planes, []uint32{overlay.ID}) {
+		t.Errorf("Expected only the overlay plane but got %v", res.Planes)
+	}
+
+	if err := dev.SetClientCap(drm.ClientCapUniversalPlanes, 1); err != nil {
+		t.Fatal(err)
+	}
+	res, err = mode.GetPlaneResources(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint32{primary.ID, overlay.ID, cursor.ID}
+	if !reflect.DeepEqual(res.Planes, expected) {
+		t.Errorf("Expected planes %v but got %v", expected, res.Planes)
+	}
+
+	plane, err := mode.GetPlane(dev, overlay.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plane.ID != overlay.ID || plane.CrtcID != card.Crtcs[0].ID ||
+		plane.PossibleCrtcs != 1 ||
+		!reflect.DeepEqual(plane.Formats, overlay.Formats) {
+		t.Errorf("Unexpected plane: %+v", plane)
+	}
+	if _, err := mode.GetPlane(dev, 1000); err == nil {
+		t.Errorf("Expected error for an invalid plane")
+	}
+
+	for _, fake := range []*drmtest.Plane{primary, overlay, cursor} {
+		typ, err := mode.GetPlaneType(dev, fake.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != fake.PropValues[0] {
+			t.Errorf("Plane %d: expected type %d but got %d", fake.ID,
+				fake.PropValues[0], typ)
+		}
+	}
+}
+
+func TestGetPlaneFormatsChanged(t *testing.T) {
+	card := drmtest.New()
+	fake := card.AddPlane(mode.PlaneOverlay, 1, fourcc.XRGB8888)
+	dev := openFake(t, card)
+
+	var calls int
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeGetPlane {
+			return
+		}
+		calls++
+		if calls == 2 {
+			card.Lock()
+			fake.Formats = []fourcc.Format{fourcc.XRGB8888, fourcc.NV12}
+			card.Unlock()
+		}
+	}
+	plane, err := mode.GetPlane(dev, fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 || !reflect.DeepEqual(plane.Formats, fake.Formats) {
+		t.Errorf("Unexpected formats %v after %d requests", plane.Formats,
+			calls)
+	}
+}
+
+func TestSetPlane(t *testing.T) {
+	card := drmtest.New()
+	card.AddHead(drmtest.Mode(1920, 1080, 60))
+	card.AddHead(drmtest.Mode(1920, 1080, 60))
+	fake := card.AddPlane(mode.PlaneOverlay, 1, fourcc.XRGB8888)
+	crtcID := card.Crtcs[0].ID
+	dev := openFake(t, card)
+
+	fb, err := dev.CreateFB(640, 480, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbID, err := dev.AddFB(640, 480, 24, 32, fb.Pitch, fb.Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upscale the left half of the frame to the whole screen
+	dst := image.Rect(0, 0, 1920, 1080)
+	src := mode.FixedRect{W: mode.Fixed16Float(319.5), H: mode.Fixed16Int(480)}
+	if err := dev.SetPlane(fake.ID, crtcID, fbID, dst, src); err != nil {
+		t.Fatal(err)
+	}
+	plane, err := dev.GetPlane(fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plane.CrtcID != crtcID || plane.FbID != fbID {
+		t.Errorf("Plane not enabled: %+v", plane)
+	}
+
+	for _, test := range []struct {
+		crtcID   uint32
+		src      mode.FixedRect
+		expected error
+	}{
+		{card.Crtcs[1].ID, src, drm.ErrInvalidMode},
+		{crtcID, mode.FixedRectOf(image.Rect(1, 0, 641, 480)), drm.ErrInvalidMode},
+		{1000, src, drm.ErrNotFound},
+	} {
+		err := dev.SetPlane(fake.ID, test.crtcID, fbID, dst, test.src)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v but got %v", test.expected, err)
+		}
+	}
+
+	if err := dev.SetPlane(fake.ID, 0, 0, image.Rectangle{}, mode.FixedRect{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.CrtcID != 0 || fake.FbID != 0 {
+		t.Errorf("Plane not disabled: %+v", fake)
+	}
+}
+
+func TestFixed16(t *testing.T) {
+	for _, test := range []struct {
+		val   mode.Fixed16
+		i     int
+		f     float64
+		str   string
+		whole uint32
+	}{
+		{mode.Fixed16Int(0), 0, 0, "0", 0},
+		{mode.Fixed16Int(1920), 1920, 1920, "1920", 1920 << 16},
+		{mode.Fixed16Float(1.5), 1, 1.5, "1.5", 0x18000},
+		{mode.Fixed16Float(0.25), 0, 0.25, "0.25", 0x4000},
+		{mode.Fixed16Int(65535), 65535, 65535, "65535", 0xffff0000},
+		{mode.Fixed16Float(65535.99999), 65535, mode.MaxFixed16.Float64(),
+			mode.MaxFixed16.String(), 0xffffffff},
+		// out of range
+		{mode.Fixed16Int(-1), 0, 0, "0", 0},
+		{mode.Fixed16Int(65536), 65535, mode.MaxFixed16.Float64(),
+			mode.MaxFixed16.String(), 0xffffffff},
+		{mode.Fixed16Float(-0.5), 0, 0, "0", 0},
+		{mode.Fixed16Float(1e10), 65535, mode.MaxFixed16.Float64(),
+			mode.MaxFixed16.String(), 0xffffffff},
+		{mode.Fixed16Float(math.NaN()), 0, 0, "0", 0},
+	} {
+		if test.val.Int() != test.i || test.val.Float64() != test.f ||
+			test.val.String() != test.str || uint32(test.val) != test.whole {
+			t.Errorf("Unexpected conversions of %#x: %d %g %s", uint32(test.val),
+				test.val.Int(), test.val.Float64(), test.val)
+		}
+	}
+
+	r := image.Rect(10, 20, 330, 260)
+	fixed := mode.FixedRectOf(r)
+	if fixed.X.Int() != 10 || fixed.Y.Int() != 20 ||
+		fixed.W.Int() != 320 || fixed.H.Int() != 240 {
+		t.Errorf("Unexpected rectangle: %+v", fixed)
+	}
+	if fixed.Rect() != r {
+		t.Errorf("Expected %v but got %v", r, fixed.Rect())
+	}
+}
@@ -0,0 +1,378 @@
+package mode
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+// Object types
+const (
+	ObjectCrtc      = 0xcccccccc
+	ObjectConnector = 0xc0c0c0c0
+	ObjectEncoder   = 0xe0e0e0e0
+	ObjectMode      = 0xdededede
+	ObjectProperty  = 0xb0b0b0b0
+	ObjectFB        = 0xfbfbfbfb
+	ObjectBlob      = 0xbbbbbbbb
+	ObjectPlane     = 0xeeeeeeee
+	ObjectAny       = 0
+)
+
+// Property flags
+const (
+	PropPending   = 1 << 0
+	PropRange     = 1 << 1
+	PropImmutable = 1 << 2
+	PropEnum      = 1 << 3
+	PropBlob      = 1 << 4
+	PropBitmask   = 1 << 5
+
+	PropExtendedType = 0x0000ffc0
+	PropObject       = 1 << 6
+	PropSignedRange  = 2 << 6
+
+	PropAtomic = 0x80000000
+)
+
+// ErrNoProperty is returned when setting or reading a property the
+// object doesn't have.
+var ErrNoProperty = errors.New("mode: no such property")
+
+type (
+	sysPropertyEnum struct {
+		value uint64
+		name  [PropNameLen]uint8
+	}
+
+	sysGetProperty struct {
+		valuesPtr      uint64
+		enumBlobPtr    uint64
+		id             uint32
+		flags          uint32
+		name           [PropNameLen]uint8
+		countValues    uint32
+		countEnumBlobs uint32
+	}
+
+	sysObjGetProperties struct {
+		propsPtr      uint64
+		propValuesPtr uint64
+		countProps    uint32
+		objID         uint32
+		objType       uint32
+	}
+
+	sysObjSetProperty struct {
+		value   uint64
+		propID  uint32
+		objID   uint32
+		objType uint32
+	}
+
+	PropertyEnum struct {
+		Value uint64
+		Name  string
+	}
+
+	Property struct {
+		ID    uint32
+		Name  string
+		Flags uint32
+
+		// Values are the limits of range properties, the values of
+		// enum properties or the type of object properties.
+		Values []uint64
+		Enums  []PropertyEnum // of enum and bitmask properties
+	}
+
+	ObjectProperties struct {
+		Props      []uint32
+		PropValues []uint64
+	}
+
+	// PropertySet are the properties of a mode-setting object, keyed
+	// by name (eg.: "DPMS", "EDID", "CRTC_ID").
+	PropertySet struct {
+		ObjID, ObjType uint32
+
+		Props  map[string]*Property
+		Values map[string]uint64
+	}
+)
+
+var (
+	// DRM_IOWR(0xAA, struct drm_mode_get_property)
+	IOCTLModeGetProperty = ioctl.Register("DRM_IOCTL_MODE_GETPROPERTY",
+		ioctl.IOWR(ioctlBase, 0xAA, sysGetProperty{}))
+
+	// DRM_IOWR(0xB9, struct drm_mode_obj_get_properties)
+	IOCTLModeObjGetProperties = ioctl.Register("DRM_IOCTL_MODE_OBJ_GETPROPERTIES",
+		ioctl.IOWR(ioctlBase, 0xB9, sysObjGetProperties{}))
+
+	// DRM_IOWR(0xBA, struct drm_mode_obj_set_property)
+	IOCTLModeObjSetProperty = ioctl.Register("DRM_IOCTL_MODE_OBJ_SETPROPERTY",
+		ioctl.IOWR(ioctlBase, 0xBA, sysObjSetProperty{}))
+)
+
+// GetProperty returns the property id, with its enum values or its
+// range limits.
+func GetProperty(file ioctl.File, id uint32) (*Property, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	var (
+		prop   *sysGetProperty
+		values []uint64
+		enums  []sysPropertyEnum
+	)
+
+	// same two-phase pattern of GetResources, the driver may change
+	// the enums in-between (eg.: when a connector is hotplugged).
+	for {
+		prop = &sysGetProperty{}
+		prop.id = id
+		err := ioctl.Call(file, uintptr(IOCTLModeGetProperty),
+			pins.Ptr(unsafe.Pointer(prop)))
+		if err != nil {
+			return nil, err
+		}
+		values, enums = nil, nil
+		if prop.countValues > 0 {
+			values = make([]uint64, prop.countValues)
+			prop.valuesPtr = pins.Addr(unsafe.Pointer(&values[0]))
+		}
+		if prop.countEnumBlobs > 0 && prop.flags&(PropEnum|PropBitmask) != 0 {
+			enums = make([]sysPropertyEnum, prop.countEnumBlobs)
+			prop.enumBlobPtr = pins.Addr(unsafe.Pointer(&enums[0]))
+		} else {
+			prop.countEnumBlobs = 0
+		}
+
+		err = ioctl.Call(file, uintptr(IOCTLModeGetProperty),
+			pins.Ptr(unsafe.Pointer(prop)))
+		if err != nil {
+			return nil, err
+		}
+		// compare to what was allocated: an enum property may have no
+		// enums at the first request
+		if prop.countValues <= uint32(len(values)) &&
+			(prop.flags&(PropEnum|PropBitmask) == 0 ||
+				prop.countEnumBlobs <= uint32(len(enums))) {
+			break
+		}
+	}
+
+	ret := &Property{
+		ID:     prop.id,
+		Name:   cString(prop.name[:]),
+		Flags:  prop.flags,
+		Values: values[:prop.countValues],
+	}
+	if prop.flags&(PropEnum|PropBitmask) != 0 {
+		for _, enum := range enums[:prop.countEnumBlobs] {
+			ret.Enums = append(ret.Enums, PropertyEnum{
+				Value: enum.value,
+				Name:  cString(enum.name[:]),
+			})
+		}
+	}
+	return ret, nil
+}
+
+// GetObjectProperties returns the ids and values of the properties of
+// the object objID of type objType (eg.: ObjectCrtc).
+func GetObjectProperties(file ioctl.File, objID, objType uint32) (*ObjectProperties, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	var (
+		obj    *sysObjGetProperties
+		props  []uint32
+		values []uint64
+	)
+	for {
+		obj = &sysObjGetProperties{}
+		obj.objID = objID
+		obj.objType = objType
+		err := ioctl.Call(file, uintptr(IOCTLModeObjGetProperties),
+			pins.Ptr(unsafe.Pointer(obj)))
+		if err != nil {
+			return nil, err
+		}
+		counts := *obj
+
+		props, values = nil, nil
+		if obj.countProps > 0 {
+			props = make([]uint32, obj.countProps)
+			obj.propsPtr = pins.Addr(unsafe.Pointer(&props[0]))
+			values = make([]uint64, obj.countProps)
+			obj.propValuesPtr = pins.Addr(unsafe.Pointer(&values[0]))
+		}
+
+		err = ioctl.Call(file, uintptr(IOCTLModeObjGetProperties),
+			pins.Ptr(unsafe.Pointer(obj)))
+		if err != nil {
+			return nil, err
+		}
+		if obj.countProps <= counts.countProps {
+			break
+		}
+	}
+	return &ObjectProperties{
+		Props:      props[:obj.countProps],
+		PropValues: values[:obj.countProps],
+	}, nil
+}
+
+// SetObjectProperty sets the property propID of the object objID of
+// type objType to value. Only the DRM master can set properties.
+func SetObjectProperty(file ioctl.File, objID, objType, propID uint32, value uint64) error {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	req := &sysObjSetProperty{
+		value:   value,
+		propID:  propID,
+		objID:   objID,
+		objType: objType,
+	}
+	return ioctl.Call(file, uintptr(IOCTLModeObjSetProperty),
+		pins.Ptr(unsafe.Pointer(req)))
+}
+
+// GetPropertySet returns the properties of the object objID of type
+// objType, along with their values, keyed by name.
+func GetPropertySet(file ioctl.File, objID, objType uint32) (*PropertySet, error) {
+	obj, err := GetObjectProperties(file, objID, objType)
+	if err != nil {
+		return nil, err
+	}
+	set := &PropertySet{
+		ObjID:   objID,
+		ObjType: objType,
+		Props:   make(map[string]*Property, len(obj.Props)),
+		Values:  make(map[string]uint64, len(obj.Props)),
+	}
+	for i, id := range obj.Props {
+		prop, err := GetProperty(file, id)
+		if err != nil {
+			return nil, err
+		}
+		set.Props[prop.Name] = prop
+		set.Values[prop.Name] = obj.PropValues[i]
+	}
+	return set, nil
+}
+
+// Value returns the value of the property called name.
+func (s *PropertySet) Value(name string) (uint64, bool) {
+	val, ok := s.Values[name]
+	return val, ok
+}
+
+// Set sets the property called name to value, on the device and in
+// the set. Enum properties can also be set by the name of the enum
+// value, see Property.EnumValue.
+func (s *PropertySet) Set(file ioctl.File, name string, value uint64) error {
+	prop, ok := s.Props[name]
+	if !ok {
+		return fmt.Errorf("%w: object %d has no property %q",
+			ErrNoProperty, s.ObjID, name)
+	}
+	err := SetObjectProperty(file, s.ObjID, s.ObjType, prop.ID, value)
+	if err != nil {
+		return err
+	}
+	s.Values[name] = value
+	return nil
+}
+
+// hasType tells if the property is of type typ, one of PropRange,
+// PropEnum, PropBlob, PropBitmask, PropObject or PropSignedRange.
+func (p *Property) hasType(typ uint32) bool {
+	if typ&PropExtendedType != 0 {
+		return p.Flags&PropExtendedType == typ
+	}
+	return p.Flags&typ != 0
+}
+
+func (p *Property) IsRange() bool       { return p.hasType(PropRange) }
+func (p *Property) IsSignedRange() bool { return p.hasType(PropSignedRange) }
+func (p *Property) IsEnum() bool        { return p.hasType(PropEnum) }
+func (p *Property) IsBitmask() bool     { return p.hasType(PropBitmask) }
+func (p *Property) IsBlob() bool        { return p.hasType(PropBlob) }
+func (p *Property) IsObject() bool      { return p.hasType(PropObject) }
+func (p *Property) IsImmutable() bool   { return p.Flags&PropImmutable != 0 }
+func (p *Property) IsAtomic() bool      { return p.Flags&PropAtomic != 0 }
+
+// Range returns the limits of a range property.
+func (p *Property) Range() (min, max uint64) {
+	if len(p.Values) < 2 {
+		return 0, 0
+	}
+	return p.Values[0], p.Values[1]
+}
+
+// SignedRange returns the limits of a signed range property.
+func (p *Property) SignedRange() (min, max int64) {
+	umin, umax := p.Range()
+	return int64(umin), int64(umax)
+}
+
+// ObjectType returns the type of the objects an object property
+// refers to.
+func (p *Property) ObjectType() uint32 {
+	if len(p.Values) < 1 {
+		return 0
+	}
+	return uint32(p.Values[0])
+}
+
+// EnumValue returns the value of the enum called name. For bitmask
+// properties it's the bit number, not the mask.
+func (p *Property) EnumValue(name string) (uint64, bool) {
+	for _, enum := range p.Enums {
+		if enum.Name == name {
+			return enum.Value, true
+		}
+	}
+	return 0, false
+}
+
+// EnumName returns the name of the enum value.
+func (p *Property) EnumName(value uint64) (string, bool) {
+	for _, enum := range p.Enums {
+		if enum.Value == value {
+			return enum.Name, true
+		}
+	}
+	return "", false
+}
+
+// propertyName returns the name of the property id, without fetching
+// its values.
+func propertyName(file ioctl.File, id uint32) (string, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	prop := &sysGetProperty{}
+	prop.id = id
+	err := ioctl.Call(file, uintptr(IOCTLModeGetProperty),
+		pins.Ptr(unsafe.Pointer(prop)))
+	if err != nil {
+		return "", err
+	}
+	return cString(prop.name[:]), nil
+}
+
+func cString(b []uint8) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,221 @@
+package mode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/drm"
+	"github.com/NeowayLabs/drm/drmtest"
+	"github.com/NeowayLabs/drm/mode"
+)
+
+func TestGetProperty(t *testing.T) {
+	card := drmtest.New()
+	conn := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	dpms := card.AddProperty(&drmtest.Property{
+		Name:   "DPMS",
+		Flags:  mode.PropEnum,
+		Values: []uint64{0, 1, 2, 3},
+		Enums: []mode.PropertyEnum{
+			{Value: 0, Name: "On"},
+			{Value: 1, Name: "Standby"},
+			{Value: 2, Name: "Suspend"},
+			{Value: 3, Name: "Off"},
+		},
+	})
+	gamma := card.AddProperty(&drmtest.Property{
+		Name:   "GAMMA_LUT_SIZE",
+		Flags:  mode.PropRange | mode.PropImmutable,
+		Values: []uint64{0, 4096},
+	})
+	offset := card.AddProperty(&drmtest.Property{
+		Name:   "offset",
+		Flags:  mode.PropSignedRange,
+		Values: []uint64{uint64(1<<64 - 100), 100},
+	})
+	crtcID := card.AddProperty(&drmtest.Property{
+		Name:   "CRTC_ID",
+		Flags:  mode.PropObject | mode.PropAtomic,
+		Values: []uint64{mode.ObjectCrtc},
+	})
+	card.Attach(conn.ID, dpms, 0)
+	dev := openFake(t, card)
+
+	prop, err := mode.GetProperty(dev, dpms.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prop.Name != "DPMS" || !prop.IsEnum() || prop.IsRange() ||
+		prop.IsBitmask() || prop.IsImmutable() ||
+		!reflect.DeepEqual(prop.Enums, dpms.Enums) {
+		t.Errorf("Unexpected property: %+v", prop)
+	}
+	if val, ok := prop.EnumValue("Off"); !ok || val != 3 {
+		t.Errorf("Unexpected Off value: %d", val)
+	}
+	if name, ok := prop.EnumName(1); !ok || name != "Standby" {
+		t.Errorf("Unexpected name of 1: %s", name)
+	}
+
+	prop, err = mode.GetProperty(dev, gamma.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := prop.Range(); !prop.IsRange() || !prop.IsImmutable() ||
+		min != 0 || max != 4096 || prop.Enums != nil {
+		t.Errorf("Unexpected property: %+v", prop)
+	}
+
+	prop, err = mode.GetProperty(dev, offset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := prop.SignedRange(); !prop.IsSignedRange() ||
+		prop.IsObject() || min != -100 || max != 100 {
+		t.Errorf("Unexpected property: %+v", prop)
+	}
+
+	prop, err = mode.GetProperty(dev, crtcID.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prop.IsObject() || prop.IsSignedRange() || !prop.IsAtomic() ||
+		prop.ObjectType() != mode.ObjectCrtc {
+		t.Errorf("Unexpected property: %+v", prop)
+	}
+
+	if _, err := mode.GetProperty(dev, 1000); !errors.Is(err, drm.ErrNotFound) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotFound, err)
+	}
+}
+
+func TestGetPropertyEnumsChanged(t *testing.T) {
+	card := drmtest.New()
+	fake := card.AddProperty(&drmtest.Property{
+		Name:  "Broadcast RGB",
+		Flags: mode.PropEnum,
+		Enums: []mode.PropertyEnum{{Value: 0, Name: "Automatic"}},
+	})
+	dev := openFake(t, card)
+
+	var calls int
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeGetProperty {
+			return
+		}
+		calls++
+		if calls == 2 {
+			card.Lock()
+			fake.Enums = append(fake.Enums,
+				mode.PropertyEnum{Value: 1, Name: "Full"},
+				mode.PropertyEnum{Value: 2, Name: "Limited 16:235"})
+			card.Unlock()
+		}
+	}
+	prop, err := mode.GetProperty(dev, fake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 || !reflect.DeepEqual(prop.Enums, fake.Enums) {
+		t.Errorf("Unexpected enums %v after %d requests", prop.Enums, calls)
+	}
+
+	// no enums at the first request
+	empty := card.AddProperty(&drmtest.Property{
+		Name:   "content type",
+		Flags:  mode.PropEnum,
+		Values: []uint64{0, 1},
+	})
+	calls = 0
+	card.BeforeRequest = func(code uint32) {
+		if code != mode.IOCTLModeGetProperty {
+			return
+		}
+		calls++
+		if calls == 2 {
+			card.Lock()
+			empty.Enums = []mode.PropertyEnum{
+				{Value: 0, Name: "No Data"},
+				{Value: 1, Name: "Graphics"},
+			}
+			card.Unlock()
+		}
+	}
+	prop, err = mode.GetProperty(dev, empty.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 || !reflect.DeepEqual(prop.Enums, empty.Enums) {
+		t.Errorf("Unexpected enums %v after %d requests", prop.Enums, calls)
+	}
+}
+
+func TestPropertySet(t *testing.T) {
+	card := drmtest.New()
+	conn := card.AddHead(drmtest.Mode(1920, 1080, 60))
+	dpms := card.AddProperty(&drmtest.Property{
+		Name:  "DPMS",
+		Flags: mode.PropEnum,
+		Enums: []mode.PropertyEnum{
+			{Value: 0, Name: "On"},
+			{Value: 3, Name: "Off"},
+		},
+	})
+	edid := card.AddProperty(&drmtest.Property{
+		Name:  "EDID",
+		Flags: mode.PropBlob | mode.PropImmutable,
+	})
+	card.Attach(conn.ID, dpms, 0)
+	card.Attach(conn.ID, edid, 42)
+	master := openFake(t, card)
+	other := openFake(t, card)
+
+	obj, err := mode.GetObjectProperties(master, conn.ID, mode.ObjectConnector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(obj.Props, []uint32{dpms.ID, edid.ID}) ||
+		!reflect.DeepEqual(obj.PropValues, []uint64{0, 42}) {
+		t.Errorf("Unexpected properties: %+v", obj)
+	}
+	if _, err := mode.GetObjectProperties(master, conn.ID, mode.ObjectPlane); err == nil {
+		t.Errorf("Expected error for the wrong object type")
+	}
+
+	set, err := mode.GetPropertySet(master, conn.ID, mode.ObjectConnector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, ok := set.Value("EDID"); !ok || val != 42 {
+		t.Errorf("Unexpected EDID blob %d", val)
+	}
+	off, _ := set.Props["DPMS"].EnumValue("Off")
+	if err := set.Set(master, "DPMS", off); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := card.PropValue(conn.ID, dpms)
+	if val, _ := set.Value("DPMS"); val != off || current != off {
+		t.Errorf("DPMS not set: %d", current)
+	}
+
+	for _, test := range []struct {
+		file     *drm.Device
+		name     string
+		value    uint64
+		expected error
+	}{
+		{other, "DPMS", 0, drm.ErrNotMaster},
+		{master, "DPMS", 1, drm.ErrInvalidMode},
+		{master, "EDID", 0, drm.ErrInvalidMode},
+		{master, "link-status", 0, mode.ErrNoProperty},
+	} {
+		err := set.Set(test.file, test.name, test.value)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, err)
+		}
+	}
+	if val, _ := set.Value("DPMS"); val != off {
+		t.Errorf("Value changed by a failed set: %d", val)
+	}
+}
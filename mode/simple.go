@@ -5,21 +5,23 @@ package mode
 import (
 	"fmt"
 	_ "image/jpeg"
-	"os"
+
+	"github.com/NeowayLabs/drm/ioctl"
 )
 
 type (
 	Modeset struct {
 		Width, Height uint16
 
-		Mode Info
-		Conn uint32
-		Crtc uint32
+		Mode  Info
+		Conn  uint32
+		Crtc  uint32
+		Plane uint32 // primary plane, found only by NewAtomicModeset
 	}
 
 	SimpleModeset struct {
 		Modesets []Modeset
-		driFile  *os.File
+		driFile  ioctl.File
 	}
 )
 
@@ -68,7 +70,7 @@ func (mset *SimpleModeset) setupDev(res *Resources, conn *Connector, dev *Modese
 
 	err := mset.findCrtc(res, conn, dev)
 	if err != nil {
-		return false, fmt.Errorf("no valid crtc for connector %u: %s", conn.ID, err.Error())
+		return false, fmt.Errorf("no valid crtc for connector %d: %s", conn.ID, err.Error())
 	}
 
 	return true, nil
@@ -118,7 +120,7 @@ func (mset *SimpleModeset) findCrtc(res *Resources, conn *Connector, dev *Modese
 		// iterate all global CRTCs
 		for j := 0; j < len(res.Crtcs); j++ {
 			// check whether this CRTC works with the encoder
-			if (encoder.PossibleCrtcs & (1 << uint(j))) != 0 {
+			if (encoder.PossibleCrtcs & (1 << uint(j))) == 0 {
 				continue
 			}
 
@@ -158,7 +160,7 @@ func (mset *SimpleModeset) SetCrtc(dev *Modeset, savedCrtc *Crtc) error {
 	return nil
 }
 
-func NewSimpleModeset(file *os.File) (*SimpleModeset, error) {
+func NewSimpleModeset(file ioctl.File) (*SimpleModeset, error) {
 	var err error
 
 	mset := &SimpleModeset{
@@ -0,0 +1,168 @@
+package drm
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/NeowayLabs/drm/ioctl"
+)
+
+type (
+	// union drm_wait_vblank: the request and the reply share the type
+	// and sequence; the signal of the request holds the user data of
+	// the event and is overwritten by the reply time. The time fields
+	// are C longs, as wide as int on Linux.
+	waitVBlank struct {
+		typ      uint32
+		sequence uint32
+		tvalSec  int
+		tvalUsec int
+	}
+
+	crtcGetSequence struct {
+		crtcID     uint32
+		active     uint32
+		sequence   uint64
+		sequenceNs int64
+	}
+
+	crtcQueueSequence struct {
+		crtcID   uint32
+		flags    uint32
+		sequence uint64
+		userData uint64
+	}
+
+	// VBlank is the vblank counter of a CRTC, and the time of its last
+	// vertical blank, CLOCK_MONOTONIC if the driver has
+	// CapTimestampMonotonic.
+	VBlank struct {
+		Sequence uint64
+		Time     time.Duration
+	}
+
+	// CrtcSequence is VBlank plus the state of the CRTC: while a CRTC is
+	// off, its counter does not advance.
+	CrtcSequence struct {
+		VBlank
+		Active bool
+	}
+)
+
+// Flags of WaitVBlank
+const (
+	VBlankAbsolute   = 0x00000000 // sequence is a vblank count
+	VBlankRelative   = 0x00000001 // sequence is added to the current count
+	VBlankEvent      = 0x04000000 // send an event instead of blocking
+	VBlankNextOnMiss = 0x10000000 // if missed, wait for the next vblank
+
+	vblankSecondary     = 0x20000000
+	vblankHighCrtcMask  = 0x0000003e
+	vblankHighCrtcShift = 1
+)
+
+// Flags of QueueCrtcSequence
+const (
+	CrtcSequenceRelative   = 0x00000001
+	CrtcSequenceNextOnMiss = 0x00000002
+)
+
+// WaitVBlank waits for the vblank sequence of the CRTC at crtcIndex in
+// the CRTCs of mode.GetResources, counted from the current one with
+// VBlankRelative. With VBlankEvent, it returns at once and an
+// EventVBlank carrying userData is sent at that vblank. The returned
+// sequence is the vblank waited for, or the target of the event.
+//
+// The vblank counter of this legacy request is 32 bits wide, as is
+// userData on 32 bits systems, and the CRTCs past the second need
+// CapVBlankHighCRTC; GetCrtcSequence and QueueCrtcSequence have none
+// of these limits.
+func WaitVBlank(file ioctl.File, crtcIndex int, flags, sequence uint32, userData uint64) (VBlank, error) {
+	getCap := func(capid uint64) (uint64, error) { return GetCap(file, capid) }
+	return vblankWait(file, getCap, crtcIndex, flags, sequence, userData)
+}
+
+// vblankWait is WaitVBlank, reading CapVBlankHighCRTC with getCap.
+func vblankWait(file ioctl.File, getCap func(uint64) (uint64, error),
+	crtcIndex int, flags, sequence uint32, userData uint64) (VBlank, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	switch {
+	case crtcIndex < 0 || crtcIndex > vblankHighCrtcMask>>vblankHighCrtcShift:
+		return VBlank{}, fmt.Errorf("drm: invalid CRTC index %d", crtcIndex)
+	case crtcIndex == 1:
+		flags |= vblankSecondary
+	case crtcIndex > 1:
+		high, err := getCap(CapVBlankHighCRTC)
+		if err != nil {
+			return VBlank{}, err
+		}
+		if high == 0 {
+			return VBlank{}, ErrUnsupported
+		}
+		flags |= uint32(crtcIndex<<vblankHighCrtcShift) & vblankHighCrtcMask
+	}
+	req := &waitVBlank{
+		typ:      flags,
+		sequence: sequence,
+		tvalSec:  int(userData),
+	}
+	err := ioctl.Call(file, uintptr(IOCTLWaitVBlank),
+		pins.Ptr(unsafe.Pointer(req)))
+	if err != nil {
+		return VBlank{}, err
+	}
+	if flags&VBlankEvent != 0 {
+		// no time in the reply, the time comes with the event
+		return VBlank{Sequence: uint64(req.sequence)}, nil
+	}
+	return VBlank{
+		Sequence: uint64(req.sequence),
+		Time: time.Duration(req.tvalSec)*time.Second +
+			time.Duration(req.tvalUsec)*time.Microsecond,
+	}, nil
+}
+
+// GetCrtcSequence returns the 64 bits vblank counter of the CRTC
+// crtcID, with the time of its last vblank in nanoseconds.
+func GetCrtcSequence(file ioctl.File, crtcID uint32) (CrtcSequence, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	req := &crtcGetSequence{crtcID: crtcID}
+	err := ioctl.Call(file, uintptr(IOCTLCrtcGetSequence),
+		pins.Ptr(unsafe.Pointer(req)))
+	if err != nil {
+		return CrtcSequence{}, err
+	}
+	return CrtcSequence{
+		VBlank: VBlank{
+			Sequence: req.sequence,
+			Time:     time.Duration(req.sequenceNs),
+		},
+		Active: req.active != 0,
+	}, nil
+}
+
+// QueueCrtcSequence requests an EventCrtcSequence carrying userData at
+// the vblank sequence of the CRTC crtcID, counted from the current one
+// with CrtcSequenceRelative. It returns the sequence queued.
+func QueueCrtcSequence(file ioctl.File, crtcID, flags uint32, sequence, userData uint64) (uint64, error) {
+	var pins ioctl.Pins
+	defer pins.Unpin()
+
+	req := &crtcQueueSequence{
+		crtcID:   crtcID,
+		flags:    flags,
+		sequence: sequence,
+		userData: userData,
+	}
+	err := ioctl.Call(file, uintptr(IOCTLCrtcQueueSequence),
+		pins.Ptr(unsafe.Pointer(req)))
+	if err != nil {
+		return 0, err
+	}
+	return req.sequence, nil
+}
@@ -0,0 +1,140 @@
+package drm_test
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/drm"
+)
+
+func TestWaitVBlank(t *testing.T) {
+	card, dev, _ := flipCard(t)
+	crtc := card.Crtcs[1]
+
+	type result struct {
+		vblank drm.VBlank
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		vblank, err := dev.WaitVBlank(1, drm.VBlankRelative, 2, 0)
+		done <- result{vblank, err}
+	}()
+	var res result
+wait:
+	for timeout := time.After(5 * time.Second); ; {
+		select {
+		case res = <-done:
+			break wait
+		case <-timeout:
+			t.Fatal("WaitVBlank not done")
+		case <-time.After(time.Millisecond):
+			card.VBlank()
+		}
+	}
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	card.Lock()
+	if res.vblank.Sequence < 2 || res.vblank.Sequence > crtc.Sequence ||
+		res.vblank.Time == 0 {
+		t.Errorf("Unexpected vblank %+v, at sequence %d", res.vblank, crtc.Sequence)
+	}
+	card.Unlock()
+
+	current := card.Crtcs[0].Sequence
+	vblank, err := dev.WaitVBlank(0, drm.VBlankEvent|drm.VBlankNextOnMiss, 0, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vblank.Sequence != current+1 {
+		t.Errorf("Expected sequence %d but got %d", current+1, vblank.Sequence)
+	}
+	card.VBlank()
+	events, err := dev.ReadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != drm.EventVBlank ||
+		events[0].UserData != 42 || events[0].Sequence != current+1 ||
+		events[0].Time != card.Crtcs[0].VBlankTime {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+
+	for _, index := range []int{-1, 32} {
+		if _, err := dev.WaitVBlank(index, 0, 0, 0); err == nil {
+			t.Errorf("Expected error waiting on the CRTC index %d", index)
+		}
+	}
+
+	card.AddCrtc()
+	card.Caps[drm.CapVBlankHighCRTC] = 0
+	var getCaps int
+	card.BeforeRequest = func(code uint32) {
+		if code == drm.IOCTLGetCap {
+			getCaps++
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := dev.WaitVBlank(2, 0, 0, 0); !errors.Is(err, drm.ErrUnsupported) {
+			t.Errorf("Expected %v but got %v", drm.ErrUnsupported, err)
+		}
+	}
+	if getCaps != 1 {
+		t.Errorf("Expected the capability read once but got %d reads", getCaps)
+	}
+	card.Caps[drm.CapVBlankHighCRTC] = 1
+	if _, err := drm.WaitVBlank(dev, 2, 0, 0, 0); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Expected %v for an inactive CRTC but got %v", syscall.EINVAL, err)
+	}
+}
+
+func TestCrtcSequence(t *testing.T) {
+	card, dev, _ := flipCard(t)
+	crtc := card.Crtcs[0]
+	card.VBlank()
+
+	seq, err := dev.GetCrtcSequence(crtc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seq.Active || seq.Sequence != 1 || seq.Time != crtc.VBlankTime {
+		t.Errorf("Unexpected sequence: %+v", seq)
+	}
+	off := card.AddCrtc()
+	if seq, err := dev.GetCrtcSequence(off.ID); err != nil || seq.Active {
+		t.Errorf("Unexpected sequence of an inactive CRTC: %+v, %v", seq, err)
+	}
+	if _, err := dev.GetCrtcSequence(1000); !errors.Is(err, drm.ErrNotFound) {
+		t.Errorf("Expected %v but got %v", drm.ErrNotFound, err)
+	}
+
+	queued, err := dev.QueueCrtcSequence(crtc.ID, drm.CrtcSequenceRelative, 2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != 3 {
+		t.Errorf("Expected sequence 3 queued but got %d", queued)
+	}
+	card.VBlank()
+	card.VBlank()
+	events, err := dev.ReadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != drm.EventCrtcSequence ||
+		events[0].UserData != 7 || events[0].Sequence != 3 ||
+		events[0].Time != crtc.VBlankTime {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+
+	queued, err = dev.QueueCrtcSequence(crtc.ID, drm.CrtcSequenceNextOnMiss, 1, 0)
+	if err != nil || queued != 4 {
+		t.Errorf("Expected the missed sequence queued at 4 but got %d, %v", queued, err)
+	}
+	if _, err := dev.QueueCrtcSequence(off.ID, 0, 1, 0); err == nil {
+		t.Errorf("Expected error for an inactive CRTC")
+	}
+}